@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/tmux"
+	"github.com/adamflitney/sesh/internal/xdg"
+	"github.com/adamflitney/sesh/internal/zoxide"
+)
+
+func runArchive(args []string) error {
+	assumeYes := false
+	var names []string
+	for _, arg := range args {
+		switch arg {
+		case "-y", "--yes":
+			assumeYes = true
+		default:
+			names = append(names, arg)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("usage: sesh archive [--yes] <project-name>")
+	}
+	name := strings.Join(names, " ")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	projects, err := finder.FindGitProjects(context.Background(), cfg.ProjectDirectories, cfg.Projects, cfg.Sort)
+	if err != nil {
+		return err
+	}
+
+	project, ok := matchProject(projects, name)
+	if !ok {
+		return fmt.Errorf("project not found: %s", name)
+	}
+
+	action := "hide it from sesh"
+	var dest string
+	if cfg.ArchiveDir != "" {
+		dest = filepath.Join(cfg.ArchiveDir, filepath.Base(project.Path))
+		if _, err := os.Stat(dest); err == nil {
+			return fmt.Errorf("archive destination already exists: %s", dest)
+		}
+		action = "move it to " + dest
+	}
+
+	if !assumeYes && !confirm(fmt.Sprintf("Archive %s (%s)? [y/N] ", project.Name, action)) {
+		return nil
+	}
+
+	// Kill the session first so nothing is left running in the directory,
+	// found as sesh connect would find it
+	if sessionName, ok := tmux.ProjectSession(project); ok {
+		if err := tmux.KillSession(sessionName, cfg.SnapshotOnKill); err != nil {
+			return err
+		}
+	}
+
+	if dest != "" {
+		if err := os.MkdirAll(cfg.ArchiveDir, xdg.DirMode()); err != nil {
+			return fmt.Errorf("failed to create archive directory: %w", err)
+		}
+		if err := os.Rename(project.Path, dest); err != nil {
+			return fmt.Errorf("failed to move project: %w", err)
+		}
+	} else if err := cache.AddArchived(project.Path); err != nil {
+		return fmt.Errorf("failed to hide project: %w", err)
+	}
+
+	_ = zoxide.Remove(project.Path)
+	_ = cache.RemoveHistory(project.Path)
+	recent, _ := cache.Load()
+	if recent != nil {
+		recent.Remove(project.Path)
+		_ = recent.Save()
+	}
+
+	fmt.Printf("Archived %s\n", project.Name)
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/finder"
+)
+
+// completionCommands are the subcommands shell completion offers
+var completionCommands = []string{
+	"list", "connect", "switch", "last", "pick", "init", "status", "dirs", "config", "templates",
+	"import", "ssh", "k8s", "undo", "snapshot", "restore", "run", "kill", "rename", "prune",
+	"archive", "doctor", "serve", "tmux", "widget", "completion", "version", "help",
+}
+
+// Completion scripts for each shell. They complete subcommands and project
+// names, the latter from plain sesh list, which answers from its last result
+// while that is current. An argument to connect starting with ./, ../, ~/
+// or / completes as a directory instead.
+const bashCompletion = `_sesh() {
+    local cur=${COMP_WORDS[COMP_CWORD]} command= i
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+        --config|--profile|--socket-name|--socket-path) ((i++)) ;;
+        -*) ;;
+        *) command=${COMP_WORDS[i]}; break ;;
+        esac
+    done
+
+    case $command in
+    "")
+        COMPREPLY=($(compgen -W "%s $(sesh list 2>/dev/null)" -- "$cur"))
+        ;;
+    connect)
+        case $cur in
+        .|./*|..|../*|\~|\~/*|/*)
+            compopt -o filenames 2>/dev/null
+            COMPREPLY=($(compgen -d -- "$cur"))
+            ;;
+        *)
+            COMPREPLY=($(compgen -W "$(sesh list 2>/dev/null)" -- "$cur"))
+            ;;
+        esac
+        ;;
+    esac
+}
+complete -F _sesh sesh
+`
+
+const zshCompletion = `#compdef sesh
+
+_sesh() {
+    local -a commands projects
+    commands=(%s)
+    if (( CURRENT == 2 )); then
+        projects=(${(f)"$(sesh list 2>/dev/null)"})
+        compadd -a commands projects
+        return
+    fi
+
+    case $words[2] in
+    connect)
+        case $PREFIX in
+        .|./*|..|../*|\~|\~/*|/*) _path_files -/ ;;
+        *)
+            projects=(${(f)"$(sesh list 2>/dev/null)"})
+            compadd -a projects
+            ;;
+        esac
+        ;;
+    esac
+}
+
+compdef _sesh sesh
+`
+
+const fishCompletion = `function __sesh_connect_args
+    set -l token (commandline -ct)
+    switch $token
+        case . './*' .. '../*' '~' '~/*' '/*'
+            __fish_complete_directories $token
+        case '*'
+            sesh list 2>/dev/null
+    end
+end
+
+complete -c sesh -f
+complete -c sesh -n __fish_use_subcommand -a "%s"
+complete -c sesh -n __fish_use_subcommand -a "(sesh list 2>/dev/null)"
+complete -c sesh -n "__fish_seen_subcommand_from connect" -a "(__sesh_connect_args)"
+`
+
+func runCompletion(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: sesh completion bash|zsh|fish")
+	}
+	commands := strings.Join(completionCommands, " ")
+	switch args[0] {
+	case "bash":
+		fmt.Printf(bashCompletion, commands)
+	case "zsh":
+		fmt.Printf(zshCompletion, commands)
+	case "fish":
+		fmt.Printf(fishCompletion, commands)
+	default:
+		return fmt.Errorf("unknown shell: %s (expected bash, zsh or fish)", args[0])
+	}
+	return nil
+}
+
+// isPathArg reports whether a connect argument names a directory rather
+// than a project: one starting with ./, ../, ~/ or /
+func isPathArg(arg string) bool {
+	if arg == "." || arg == ".." || arg == "~" || filepath.IsAbs(arg) {
+		return true
+	}
+	for _, prefix := range []string{"./", "../", "~/"} {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// connectPath opens a directory as a project named after it, whether or not
+// it is in a project directory
+func connectPath(arg string) error {
+	path, err := filepath.Abs(config.ExpandPath(arg))
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("no such directory: %s", arg)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", arg)
+	}
+	return openProject(finder.Project{Name: filepath.Base(path), Path: path})
+}
@@ -0,0 +1,29 @@
+package main
+
+import "testing"
+
+func TestIsPathArg(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want bool
+	}{
+		{".", true},
+		{"..", true},
+		{"~", true},
+		{"./api", true},
+		{"../api", true},
+		{"~/code/api", true},
+		{"/srv/api", true},
+		{"api", false},
+		{"api/web", false},
+		{"~api", false},
+		{".dotfiles", false},
+		{"api:zsh", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isPathArg(tt.arg); got != tt.want {
+			t.Errorf("isPathArg(%q) = %v, want %v", tt.arg, got, tt.want)
+		}
+	}
+}
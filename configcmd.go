@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"go.yaml.in/yaml/v3"
+)
+
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: sesh config show|dump [--json] | path | edit | validate")
+	}
+
+	switch args[0] {
+	case "show", "dump":
+		jsonOutput := false
+		for _, arg := range args[1:] {
+			if arg == "--json" {
+				jsonOutput = true
+			}
+		}
+		return dumpConfig(jsonOutput)
+	case "path":
+		// Load first so a missing config is created and the reported file exists
+		if _, err := config.LoadConfig(); err != nil {
+			return err
+		}
+		path, err := config.GetConfigFilePath()
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	case "edit":
+		return editConfig()
+	case "validate":
+		return validateConfig()
+	default:
+		return fmt.Errorf("unknown config command: %s", args[0])
+	}
+}
+
+// dumpConfig prints the fully resolved configuration, after defaults and
+// path expansion have been applied
+func dumpConfig(jsonOutput bool) error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if jsonOutput {
+		fmt.Println(string(data))
+		return nil
+	}
+
+	// Round-trip through JSON so the YAML output uses the same key names
+	var resolved map[string]any
+	if err := json.Unmarshal(data, &resolved); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(resolved); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	return enc.Close()
+}
+
+// editConfig opens the config file in $EDITOR, then validates the result
+func editConfig() error {
+	path, err := config.GetConfigFilePath()
+	if err != nil {
+		return err
+	}
+	// Loading creates the default config; an existing file is opened even if
+	// it's invalid, since fixing it is likely the point
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		if _, err := config.LoadConfig(); err != nil {
+			return err
+		}
+	}
+
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	cmd := exec.Command("sh", "-c", editor+` "$1"`, "sh", path)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor failed: %w", err)
+	}
+
+	return validateConfig()
+}
+
+// validateConfig reports problems with the config file, failing if there are any
+func validateConfig() error {
+	path, _ := config.GetConfigFilePath()
+	problems, err := config.Validate()
+	if err != nil {
+		return fmt.Errorf("%s is invalid: %w", path, err)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("%s is valid\n", path)
+		return nil
+	}
+
+	for _, p := range problems {
+		fmt.Printf("- %s\n", p)
+	}
+	return fmt.Errorf("%s has %d problem(s)", path, len(problems))
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// promptConflict asks what to do about an existing session that belongs to
+// a different directory than the project being opened
+func promptConflict(session, sessionPath, projectPath string) (tmux.ConflictChoice, error) {
+	fmt.Fprintf(os.Stderr, "Session '%s' belongs to %s, not %s\n", session, config.ContractPath(sessionPath), config.ContractPath(projectPath))
+	fmt.Fprint(os.Stderr, "[a]ttach anyway, [k]ill and recreate, [r]ename it and create a new one, or [c]ancel? ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "a", "attach":
+		return tmux.ConflictAttach, nil
+	case "k", "kill":
+		return tmux.ConflictRecreate, nil
+	case "r", "rename":
+		return tmux.ConflictRename, nil
+	default:
+		return 0, fmt.Errorf("cancelled")
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/adamflitney/sesh/internal/config"
+)
+
+func runDirs(args []string) error {
+	if len(args) == 0 {
+		return runDirsList()
+	}
+
+	switch args[0] {
+	case "list", "ls":
+		return runDirsList()
+	case "add":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: sesh dirs add <path>")
+		}
+		dir, err := normalizeDirArg(args[1])
+		if err != nil {
+			return err
+		}
+		// Remote directories are only checked when projects are listed
+		if _, _, remote := config.ParseRemote(dir); !remote {
+			if info, err := os.Stat(config.ExpandPath(dir)); err != nil || !info.IsDir() {
+				fmt.Fprintf(os.Stderr, "Warning: directory does not exist: %s\n", dir)
+			}
+		}
+		if err := config.AddProjectDirectory(dir); err != nil {
+			return err
+		}
+		fmt.Printf("Added %s\n", dir)
+		return nil
+	case "remove", "rm":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: sesh dirs remove <path>")
+		}
+		dir, err := normalizeDirArg(args[1])
+		if err != nil {
+			return err
+		}
+		if err := config.RemoveProjectDirectory(dir); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s\n", dir)
+		return nil
+	default:
+		return fmt.Errorf("unknown dirs command: %s (expected list, add or remove)", args[0])
+	}
+}
+
+func runDirsList() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+
+	for _, dir := range cfg.DirectoryPaths() {
+		if _, _, remote := config.ParseRemote(dir); remote {
+			fmt.Println(dir)
+			continue
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			fmt.Printf("%s (missing)\n", config.ContractPath(dir))
+			continue
+		}
+		fmt.Println(config.ContractPath(dir))
+	}
+	return nil
+}
+
+// normalizeDirArg turns a user supplied directory into the form stored in the
+// config file: absolute, with the home directory written as ~
+func normalizeDirArg(dir string) (string, error) {
+	if _, _, remote := config.ParseRemote(dir); remote {
+		return dir, nil
+	}
+	if dir == "~" || len(dir) > 1 && dir[:2] == "~/" {
+		return filepath.Clean(dir), nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("invalid directory %s: %w", dir, err)
+	}
+	return config.ContractPath(abs), nil
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/zoxide"
+)
+
+func runDoctor(args []string) error {
+	prune, frecency, fix := false, false, false
+	for _, arg := range args {
+		switch arg {
+		case "--prune":
+			prune = true
+		case "--frecency":
+			frecency = true
+		case "--fix":
+			fix = true
+		default:
+			return fmt.Errorf("usage: sesh doctor [--prune] [--frecency [--fix]]")
+		}
+	}
+	if fix && !frecency {
+		return fmt.Errorf("--fix goes with --frecency")
+	}
+
+	problems := 0
+	report := func(ok bool, format string, a ...any) {
+		mark := "✓"
+		if !ok {
+			mark = "✗"
+			problems++
+		}
+		fmt.Printf("%s %s\n", mark, fmt.Sprintf(format, a...))
+	}
+
+	// Loaded first as it can turn the zoxide integration off
+	configPath, _ := config.GetConfigFilePath()
+	cfg, cfgErr := config.LoadConfig()
+	if cfg != nil && !cfg.Zoxide {
+		zoxide.Disable()
+	}
+
+	checkTools(report)
+
+	// Configuration
+	report(cfgErr == nil, "config loads (%s)", configPath)
+	if cfg != nil {
+		for _, dir := range cfg.DirectoryPaths() {
+			if _, _, remote := config.ParseRemote(dir); remote {
+				continue
+			}
+			_, err := os.Stat(dir)
+			report(err == nil, "project directory exists: %s", config.ContractPath(dir))
+		}
+		for _, project := range cfg.Projects {
+			_, err := os.Stat(project.Path)
+			report(err == nil, "project exists: %s", config.ContractPath(project.Path))
+		}
+	}
+
+	// Cached references to projects that have since been deleted or moved
+	ghosts := findGhostPaths()
+	if len(ghosts) == 0 {
+		report(true, "no cached references to missing projects")
+	}
+	for _, path := range ghosts {
+		report(false, "cached project no longer exists: %s", config.ContractPath(path))
+	}
+
+	if frecency && cfg != nil {
+		fixed, err := checkFrecency(cfg, ghosts, report, fix)
+		if err != nil {
+			return err
+		}
+		problems -= fixed
+	}
+
+	if prune && len(ghosts) > 0 {
+		if err := pruneGhostPaths(ghosts); err != nil {
+			return err
+		}
+		fmt.Printf("\nPruned %d missing project(s) from the cache\n", len(ghosts))
+		return nil
+	}
+
+	if problems > 0 {
+		if len(ghosts) > 0 {
+			fmt.Println("\nRun 'sesh doctor --prune' to remove missing projects from the cache")
+		}
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+	return nil
+}
+
+// checkTools reports whether tmux and zoxide can be used
+func checkTools(report func(ok bool, format string, a ...any)) {
+	_, err := exec.LookPath("tmux")
+	report(err == nil, "tmux installed")
+	switch {
+	case zoxide.Disabled():
+		fmt.Println("- zoxide integration turned off")
+	case zoxide.IsAvailable():
+		report(true, "zoxide installed")
+	default:
+		fmt.Println("- zoxide not installed (optional, used for frecency ranking)")
+	}
+}
+
+// checkFrecency reports drift between zoxide and the projects sesh tracks:
+// zoxide entries for projects that no longer exist, and projects sesh has
+// opened that zoxide has no score for. With fix it removes the former from
+// both, along with the ghosts already reported, and adds the latter to
+// zoxide, returning how many problems it fixed.
+func checkFrecency(cfg *config.Config, ghosts []string, report func(ok bool, format string, a ...any), fix bool) (int, error) {
+	if !zoxide.IsAvailable() {
+		fmt.Println("- zoxide not in use, skipping the frecency check")
+		return 0, nil
+	}
+	scores, err := zoxide.GetScores()
+	if err != nil {
+		return 0, err
+	}
+
+	tracked := make(map[string]bool)
+	if recent, _ := cache.Load(); recent != nil {
+		for _, p := range recent.Projects {
+			tracked[p.Path] = true
+		}
+	}
+	history, _ := cache.LoadHistory()
+	for path := range history {
+		tracked[path] = true
+	}
+
+	// Stale: scored by zoxide, gone from disk, and a project sesh knows or
+	// one that was in a project directory. Other directories zoxide keeps
+	// are zoxide's business, and ghosts have been reported already.
+	var stale []string
+	for path := range scores {
+		if slices.Contains(ghosts, path) || !tracked[path] && !inProjectDirectory(cfg, path) {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			stale = append(stale, path)
+		}
+	}
+	var untracked []string
+	for path := range tracked {
+		if _, _, remote := config.ParseRemote(path); remote || scores[path] > 0 {
+			continue
+		}
+		if _, err := os.Stat(path); err == nil {
+			untracked = append(untracked, path)
+		}
+	}
+	sort.Strings(stale)
+	sort.Strings(untracked)
+
+	if len(stale) == 0 && len(untracked) == 0 && len(ghosts) == 0 {
+		report(true, "zoxide and sesh agree on your projects")
+		return 0, nil
+	}
+	for _, path := range stale {
+		report(false, "zoxide scores a project that no longer exists: %s", config.ContractPath(path))
+	}
+	for _, path := range untracked {
+		report(false, "zoxide has no score for a project sesh opened: %s", config.ContractPath(path))
+	}
+	if !fix {
+		fmt.Println("\nRun 'sesh doctor --frecency --fix' to bring zoxide in line")
+		return 0, nil
+	}
+
+	if err := pruneGhostPaths(append(stale, ghosts...)); err != nil {
+		return 0, err
+	}
+	for _, path := range untracked {
+		if err := zoxide.Add(path); err != nil {
+			return 0, fmt.Errorf("failed to add %s to zoxide: %w", path, err)
+		}
+	}
+	fmt.Printf("\nRemoved %d missing project(s) from zoxide and sesh, added %d to zoxide\n",
+		len(stale)+len(ghosts), len(untracked))
+	return len(stale) + len(untracked) + len(ghosts), nil
+}
+
+// inProjectDirectory reports whether path is below one of the local project
+// directories
+func inProjectDirectory(cfg *config.Config, path string) bool {
+	for _, dir := range cfg.DirectoryPaths() {
+		if _, _, remote := config.ParseRemote(dir); remote {
+			continue
+		}
+		if strings.HasPrefix(path, strings.TrimSuffix(dir, string(filepath.Separator))+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// findGhostPaths returns project paths referenced by sesh's caches that no
+// longer exist on disk
+func findGhostPaths() []string {
+	seen := make(map[string]bool)
+
+	if recent, _ := cache.Load(); recent != nil {
+		for _, p := range recent.Projects {
+			seen[p.Path] = true
+		}
+	}
+	archived, _ := cache.LoadArchived()
+	for path := range archived {
+		seen[path] = true
+	}
+	history, _ := cache.LoadHistory()
+	for path := range history {
+		seen[path] = true
+	}
+
+	var ghosts []string
+	for path := range seen {
+		if _, _, remote := config.ParseRemote(path); remote {
+			continue
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			ghosts = append(ghosts, path)
+		}
+	}
+	sort.Strings(ghosts)
+	return ghosts
+}
+
+// pruneGhostPaths removes the given paths from sesh's caches and zoxide
+func pruneGhostPaths(paths []string) error {
+	recent, err := cache.Load()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		recent.Remove(path)
+		if err := cache.RemoveArchived(path); err != nil {
+			return err
+		}
+		if err := cache.RemoveHistory(path); err != nil {
+			return err
+		}
+		_ = zoxide.Remove(path)
+	}
+
+	return recent.Save()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+)
+
+// importFormat describes a tool whose project files sesh import converts
+type importFormat struct {
+	dirs       []string // Where the tool keeps its files, first match wins
+	extensions []string
+	convert    func(data []byte, name string) (config.ImportedTemplate, error)
+}
+
+var importFormats = map[string]importFormat{
+	"tmuxinator": {
+		dirs:       []string{"$TMUXINATOR_CONFIG", "~/.config/tmuxinator", "~/.tmuxinator"},
+		extensions: []string{".yml", ".yaml"},
+		convert:    config.ImportTmuxinator,
+	},
+	"tmuxp": {
+		dirs:       []string{"$TMUXP_CONFIGDIR", "~/.config/tmuxp", "~/.tmuxp"},
+		extensions: []string{".yml", ".yaml", ".json"},
+		convert:    config.ImportTmuxp,
+	},
+}
+
+func runImport(args []string) error {
+	usage := fmt.Errorf("usage: sesh import tmuxinator|tmuxp [path] [--force] [--dry-run]")
+	var force, dryRun bool
+	var positional []string
+	for _, arg := range args {
+		switch arg {
+		case "-f", "--force":
+			force = true
+		case "-n", "--dry-run":
+			dryRun = true
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) < 1 || len(positional) > 2 {
+		return usage
+	}
+	format, ok := importFormats[positional[0]]
+	if !ok {
+		return fmt.Errorf("unknown format: %s (expected tmuxinator or tmuxp)", positional[0])
+	}
+
+	files, err := importFiles(format, positional[1:])
+	if err != nil {
+		return err
+	}
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, file := range files {
+		if err := importFile(format, file, force, dryRun); err != nil {
+			fmt.Fprintf(os.Stderr, "Skipped %s: %v\n", config.ContractPath(file), err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files could not be imported", failed, len(files))
+	}
+	return nil
+}
+
+// importFile converts one project file and adds it to the config as a
+// session template, or prints it with dryRun
+func importFile(format importFormat, file string, force, dryRun bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	imported, err := format.convert(data, name)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Printf("Would import %s as template %s with %d windows\n",
+			config.ContractPath(file), imported.Name, len(imported.Template.Windows))
+	} else {
+		if err := config.AddTemplate(imported.Name, imported.Template, force); err != nil {
+			if errors.Is(err, config.ErrTemplateExists) {
+				return fmt.Errorf("template %s already exists (--force replaces it)", imported.Name)
+			}
+			return err
+		}
+		fmt.Printf("Imported %s as template %s\n", config.ContractPath(file), imported.Name)
+	}
+	for _, warning := range imported.Warnings {
+		fmt.Printf("  Note: %s\n", warning)
+	}
+	if imported.Root != "" {
+		fmt.Printf("  Use it for %s with a template rule: {match: %q, template: %s}\n",
+			imported.Root, imported.Root, imported.Name)
+	}
+	return nil
+}
+
+// importFiles returns the project files to import: the file given, those in
+// the directory given, or those in the tool's own directory
+func importFiles(format importFormat, args []string) ([]string, error) {
+	var dir string
+	if len(args) == 1 {
+		path := config.ExpandPath(args[0])
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			return []string{path}, nil
+		}
+		dir = path
+	} else {
+		for _, candidate := range format.dirs {
+			if strings.HasPrefix(candidate, "$") {
+				candidate = os.Getenv(candidate[1:])
+			}
+			if candidate == "" {
+				continue
+			}
+			if info, err := os.Stat(config.ExpandPath(candidate)); err == nil && info.IsDir() {
+				dir = config.ExpandPath(candidate)
+				break
+			}
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("no project files found, pass their path")
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		for _, ext := range format.extensions {
+			if !e.IsDir() && filepath.Ext(e.Name()) == ext {
+				files = append(files, filepath.Join(dir, e.Name()))
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no project files in %s", config.ContractPath(dir))
+	}
+	return files, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+)
+
+// commonProjectDirs are offered by sesh init when they exist
+var commonProjectDirs = []string{"~/dev", "~/src", "~/code", "~/projects"}
+
+func runInit(args []string) error {
+	force := false
+	var dirs []string
+	for _, arg := range args {
+		switch arg {
+		case "-f", "--force":
+			force = true
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return fmt.Errorf("usage: sesh init [--force] [directory...]")
+			}
+			dirs = append(dirs, arg)
+		}
+	}
+
+	if path, err := config.GetConfigFilePath(); err == nil && !force {
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists, use --force to replace it", config.ContractPath(path))
+		}
+	}
+
+	if len(dirs) == 0 {
+		var found []string
+		for _, dir := range commonProjectDirs {
+			if info, err := os.Stat(config.ExpandPath(dir)); err == nil && info.IsDir() {
+				found = append(found, dir)
+			}
+		}
+		if len(found) == 0 {
+			found = []string{commonProjectDirs[0]}
+		}
+		dirs = found
+		if isTerminal() {
+			answer, err := promptProjectDirs(found)
+			if err != nil {
+				return err
+			}
+			if len(answer) > 0 {
+				dirs = answer
+			}
+		}
+	}
+
+	for i, dir := range dirs {
+		dir, err := normalizeDirArg(dir)
+		if err != nil {
+			return err
+		}
+		if info, err := os.Stat(config.ExpandPath(dir)); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Warning: directory does not exist: %s\n", dir)
+		}
+		dirs[i] = dir
+	}
+
+	path, err := config.WriteInitialConfig(dirs)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Wrote %s\n\n", config.ContractPath(path))
+
+	problems := 0
+	checkTools(func(ok bool, format string, a ...any) {
+		mark := "✓"
+		if !ok {
+			mark = "✗"
+			problems++
+		}
+		fmt.Printf("%s %s\n", mark, fmt.Sprintf(format, a...))
+	})
+	if problems > 0 {
+		return fmt.Errorf("install tmux to use sesh")
+	}
+	fmt.Println("\nRun sesh to pick a project")
+	return nil
+}
+
+// promptProjectDirs asks where projects live, offering the directories found.
+// A blank answer, accepting them, returns no directories.
+func promptProjectDirs(found []string) ([]string, error) {
+	fmt.Fprintf(os.Stderr, "Where do your projects live? Separate directories with spaces [%s]: ", strings.Join(found, " "))
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		return nil, fmt.Errorf("cancelled")
+	}
+	return strings.Fields(answer), nil
+}
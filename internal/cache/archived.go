@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// getArchivedPath returns the path to the archived projects cache file
+func getArchivedPath() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "archived.json"), nil
+}
+
+// LoadArchived returns the set of project paths hidden by sesh archive
+func LoadArchived() (map[string]bool, error) {
+	archived := make(map[string]bool)
+
+	path, err := getArchivedPath()
+	if err != nil {
+		return archived, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return archived, nil
+		}
+		return archived, err
+	}
+
+	var paths []string
+	if err := json.Unmarshal(data, &paths); err != nil {
+		return archived, nil
+	}
+	for _, p := range paths {
+		archived[p] = true
+	}
+	return archived, nil
+}
+
+// AddArchived hides a project path from the picker
+func AddArchived(projectPath string) error {
+	archived, err := LoadArchived()
+	if err != nil {
+		return err
+	}
+	archived[projectPath] = true
+	return saveArchived(archived)
+}
+
+// RemoveArchived stops hiding a project path
+func RemoveArchived(projectPath string) error {
+	archived, err := LoadArchived()
+	if err != nil {
+		return err
+	}
+	delete(archived, projectPath)
+	return saveArchived(archived)
+}
+
+// saveArchived writes the set of archived project paths
+func saveArchived(archived map[string]bool) error {
+	paths := make([]string, 0, len(archived))
+	for p := range archived {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	data, err := json.MarshalIndent(paths, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path, err := getArchivedPath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, xdg.FileMode())
+}
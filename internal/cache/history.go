@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// ProjectHistory counts how often and when a project was last opened with sesh
+type ProjectHistory struct {
+	Opens      int       `json:"opens"`
+	LastOpened time.Time `json:"last_opened"`
+}
+
+// getHistoryPath returns the path to the open history cache file
+func getHistoryPath() (string, error) {
+	cacheDir, err := ProfileDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "history.json"), nil
+}
+
+// LoadHistory returns the open history of every project, keyed by path.
+// Unlike the recent list it is never trimmed.
+func LoadHistory() (map[string]ProjectHistory, error) {
+	history := make(map[string]ProjectHistory)
+
+	path, err := getHistoryPath()
+	if err != nil {
+		return history, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return history, nil
+		}
+		return history, err
+	}
+
+	if err := json.Unmarshal(data, &history); err != nil {
+		return make(map[string]ProjectHistory), nil
+	}
+	return history, nil
+}
+
+// RecordOpen counts an open of the project at path
+func RecordOpen(projectPath string) error {
+	history, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	h := history[projectPath]
+	h.Opens++
+	h.LastOpened = time.Now()
+	history[projectPath] = h
+	return saveHistory(history)
+}
+
+// RemoveHistory forgets the open history of a project
+func RemoveHistory(projectPath string) error {
+	history, err := LoadHistory()
+	if err != nil {
+		return err
+	}
+	if _, ok := history[projectPath]; !ok {
+		return nil
+	}
+	delete(history, projectPath)
+	return saveHistory(history)
+}
+
+// saveHistory writes the open history
+func saveHistory(history map[string]ProjectHistory) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path, err := getHistoryPath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, xdg.FileMode())
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// Job is a long running command started with sesh run
+type Job struct {
+	Name    string    `json:"name"` // tmux session name
+	Command string    `json:"command"`
+	Dir     string    `json:"dir"`
+	Started time.Time `json:"started"`
+}
+
+// getJobsPath returns the path to the jobs cache file
+func getJobsPath() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "jobs.json"), nil
+}
+
+// LoadJobs returns the tracked jobs
+func LoadJobs() ([]Job, error) {
+	path, err := getJobsPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var jobs []Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, nil
+	}
+	return jobs, nil
+}
+
+// SaveJobs writes the tracked jobs
+func SaveJobs(jobs []Job) error {
+	path, err := getJobsPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, xdg.FileMode())
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// KilledWindow records a window of a killed session
+type KilledWindow struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+}
+
+// KilledSession records the most recent session killed by sesh so it can be recreated
+type KilledSession struct {
+	Session  string         `json:"session"`
+	Project  string         `json:"project"` // Project path, empty for sessions sesh didn't create
+	Windows  []KilledWindow `json:"windows"`
+	KilledAt time.Time      `json:"killed_at"`
+}
+
+// getKilledPath returns the path to the last killed session cache file
+func getKilledPath() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "last_killed.json"), nil
+}
+
+// SaveLastKilled records k as the most recently killed session
+func SaveLastKilled(k KilledSession) error {
+	path, err := getKilledPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(k, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, xdg.FileMode())
+}
+
+// LoadLastKilled returns the most recently killed session, or nil if there is none
+func LoadLastKilled() (*KilledSession, error) {
+	path, err := getKilledPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var k KilledSession
+	if err := json.Unmarshal(data, &k); err != nil {
+		return nil, nil
+	}
+	return &k, nil
+}
+
+// ClearLastKilled forgets the most recently killed session
+func ClearLastKilled() error {
+	path, err := getKilledPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
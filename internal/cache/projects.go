@@ -0,0 +1,112 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// ProjectList is the output of the last full sesh list, kept so later calls
+// can print it without loading the config or scanning
+type ProjectList struct {
+	Config  string    `json:"config"`  // Config file the list was made with
+	Options string    `json:"options"` // Command line options it was made with
+	Names   []string  `json:"names"`
+	Written time.Time `json:"written"`
+
+	// Inputs are the files the list was made from, with their modification
+	// times (zero for files that didn't exist). Any change makes it stale.
+	Inputs map[string]time.Time `json:"inputs"`
+}
+
+// getProjectListPath returns the path to the project list cache file
+func getProjectListPath() (string, error) {
+	cacheDir, err := ProfileDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "projects.json"), nil
+}
+
+// SaveProjectList records the names sesh list printed for the given config
+// file and command line options. files are the others the list was made
+// from, such as the config's own files; the caches that affect which
+// projects are listed and their order are added here.
+func SaveProjectList(configFile, options string, files, names []string) error {
+	path, err := getProjectListPath()
+	if err != nil {
+		return err
+	}
+
+	inputs := slices.Clone(files)
+	for _, getPath := range []func() (string, error){getCachePath, getHistoryPath, getArchivedPath} {
+		if p, err := getPath(); err == nil {
+			inputs = append(inputs, p)
+		}
+	}
+
+	list := ProjectList{Config: configFile, Options: options, Names: names, Written: time.Now(), Inputs: make(map[string]time.Time)}
+	for _, input := range inputs {
+		list.Inputs[input] = modTime(input)
+	}
+
+	data, err := json.Marshal(list)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, xdg.FileMode())
+}
+
+// LoadProjectList returns the names recorded by SaveProjectList if they were
+// made with the same config file and options, are younger than maxAge and
+// none of their inputs have changed since
+func LoadProjectList(configFile, options string, maxAge time.Duration) ([]string, bool) {
+	path, err := getProjectListPath()
+	if err != nil {
+		return nil, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	var list ProjectList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, false
+	}
+	if list.Config != configFile || list.Options != options || time.Since(list.Written) > maxAge {
+		return nil, false
+	}
+	for input, mtime := range list.Inputs {
+		if !modTime(input).Equal(mtime) {
+			return nil, false
+		}
+	}
+	return list.Names, true
+}
+
+// modTime returns a file's modification time, zero if it doesn't exist
+func modTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// ClearProjectList drops the recorded sesh list, e.g. once opening a project
+// has changed the order
+func ClearProjectList() error {
+	path, err := getProjectListPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProjectListInvalidation(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("XDG_STATE_HOME", filepath.Join(dir, "state"))
+	configFile := filepath.Join(dir, "config.yaml")
+	zoxideDB := filepath.Join(dir, "zoxide", "db.zo")
+	if err := os.WriteFile(configFile, []byte("editor: vim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{"api", "web"}
+	const options = "profile= no-zoxide=false"
+
+	// touch gives a file a new modification time, creating it if needed
+	touch := func(path string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			if err := os.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+		later := time.Now().Add(time.Minute)
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func()
+		config  string
+		options string
+		maxAge  time.Duration
+		want    bool
+	}{
+		{name: "unchanged", want: true},
+		{name: "config edited", change: func() { touch(configFile) }},
+		// Until zoxide first records a directory it has no database
+		{name: "zoxide database created", change: func() { touch(zoxideDB) }},
+		{name: "zoxide scores changed", change: func() { touch(zoxideDB) }},
+		{name: "project opened", change: func() {
+			recent, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			recent.Add("api", filepath.Join(dir, "api"))
+			if err := recent.Save(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "list cleared", change: func() {
+			if err := ClearProjectList(); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "other config file", config: filepath.Join(dir, "other.yaml")},
+		{name: "other options", options: "profile=work no-zoxide=false"},
+		{name: "expired", maxAge: -time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := SaveProjectList(configFile, options, []string{configFile, zoxideDB}, names); err != nil {
+				t.Fatalf("SaveProjectList: %v", err)
+			}
+			if tt.change != nil {
+				tt.change()
+			}
+
+			config, opts, maxAge := configFile, options, time.Minute
+			if tt.config != "" {
+				config = tt.config
+			}
+			if tt.options != "" {
+				opts = tt.options
+			}
+			if tt.maxAge != 0 {
+				maxAge = tt.maxAge
+			}
+			got, ok := LoadProjectList(config, opts, maxAge)
+			if ok != tt.want {
+				t.Fatalf("LoadProjectList() ok = %v, want %v", ok, tt.want)
+			}
+			if ok && !slices.Equal(got, names) {
+				t.Errorf("LoadProjectList() = %v, want %v", got, names)
+			}
+		})
+	}
+}
@@ -5,12 +5,15 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
 )
 
 // RecentProject represents a recently used project
 type RecentProject struct {
 	Name     string    `json:"name"`
 	Path     string    `json:"path"`
+	Session  string    `json:"session,omitempty"` // Set once the project's session is renamed
 	LastUsed time.Time `json:"last_used"`
 }
 
@@ -19,21 +22,76 @@ type RecentProjects struct {
 	Projects []RecentProject `json:"projects"`
 }
 
-// getCachePath returns the path to the recent projects cache file
-func getCachePath() (string, error) {
-	home, err := os.UserHomeDir()
+// Dir returns the sesh cache directory, creating it if needed
+func Dir() (string, error) {
+	cacheDir, err := xdg.CacheDir()
 	if err != nil {
 		return "", err
 	}
 
-	cacheDir := filepath.Join(home, ".cache", "sesh")
-	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+	if err := os.MkdirAll(cacheDir, xdg.DirMode()); err != nil {
+		return "", err
+	}
+
+	return cacheDir, nil
+}
+
+// profile is the config profile in use, see SetProfile
+var profile string
+
+// SetProfile keeps the files that follow what the user opens, such as the
+// recent list, apart for the given config profile. Empty means no profile.
+func SetProfile(name string) {
+	profile = name
+}
+
+// ProfileDir returns the cache directory for the current profile, creating
+// it if needed. Without a profile it is Dir itself.
+func ProfileDir() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil || profile == "" {
+		return cacheDir, err
+	}
+
+	dir := filepath.Join(cacheDir, "profiles", profile)
+	if err := os.MkdirAll(dir, xdg.DirMode()); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// recentSize is how many projects the recent list keeps, see SetRecentSize
+var recentSize = 3
+
+// SetRecentSize sets how many projects the recent list keeps
+func SetRecentSize(n int) {
+	recentSize = n
+}
+
+// getCachePath returns the path to the recent projects cache file
+func getCachePath() (string, error) {
+	cacheDir, err := ProfileDir()
+	if err != nil {
 		return "", err
 	}
 
 	return filepath.Join(cacheDir, "recent.json"), nil
 }
 
+// LastModified returns when the recent projects cache was last written
+func LastModified() (time.Time, error) {
+	cachePath, err := getCachePath()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	info, err := os.Stat(cachePath)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
 // Load reads the recent projects from cache
 func Load() (*RecentProjects, error) {
 	cachePath, err := getCachePath()
@@ -69,14 +127,17 @@ func (r *RecentProjects) Save() error {
 		return err
 	}
 
-	return os.WriteFile(cachePath, data, 0644)
+	return os.WriteFile(cachePath, data, xdg.FileMode())
 }
 
-// Add records a project as recently used
+// Add records a project as recently used. A renamed session recorded for it
+// is kept, as sesh reopens that session by the project's path.
 func (r *RecentProjects) Add(name, path string) {
 	// Remove if already exists
+	session := ""
 	for i, p := range r.Projects {
 		if p.Path == path {
+			session = p.Session
 			r.Projects = append(r.Projects[:i], r.Projects[i+1:]...)
 			break
 		}
@@ -86,19 +147,42 @@ func (r *RecentProjects) Add(name, path string) {
 	r.Projects = append([]RecentProject{{
 		Name:     name,
 		Path:     path,
+		Session:  session,
 		LastUsed: time.Now(),
 	}}, r.Projects...)
 
-	// Keep only top 3
-	if len(r.Projects) > 3 {
-		r.Projects = r.Projects[:3]
+	if len(r.Projects) > recentSize {
+		r.Projects = r.Projects[:recentSize]
+	}
+}
+
+// Remove forgets a project
+func (r *RecentProjects) Remove(path string) {
+	for i, p := range r.Projects {
+		if p.Path == path {
+			r.Projects = append(r.Projects[:i], r.Projects[i+1:]...)
+			return
+		}
+	}
+}
+
+// RenameSession records that the session of the project at path was renamed,
+// leaving the name the project is listed under alone. It reports whether the
+// list has the project.
+func (r *RecentProjects) RenameSession(path, session string) bool {
+	for i := range r.Projects {
+		if r.Projects[i].Path == path {
+			r.Projects[i].Session = session
+			return true
+		}
 	}
+	return false
 }
 
-// GetTop3 returns the top 3 most recently used projects
-func (r *RecentProjects) GetTop3() []RecentProject {
-	if len(r.Projects) > 3 {
-		return r.Projects[:3]
+// Top returns the most recently used projects, as many as the list keeps
+func (r *RecentProjects) Top() []RecentProject {
+	if len(r.Projects) > recentSize {
+		return r.Projects[:recentSize]
 	}
 	return r.Projects
 }
@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// SessionHistory records the last two sessions sesh switched to, for sesh last
+type SessionHistory struct {
+	Current  string `json:"current"`
+	Previous string `json:"previous"`
+}
+
+// getSessionHistoryPath returns the path to the session history file. It is
+// shared between profiles, like the tmux server.
+func getSessionHistoryPath() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "sessions.json"), nil
+}
+
+// LoadSessionHistory returns the sessions last switched to, empty if there
+// are none
+func LoadSessionHistory() (*SessionHistory, error) {
+	path, err := getSessionHistoryPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &SessionHistory{}, nil
+		}
+		return nil, err
+	}
+
+	var h SessionHistory
+	if err := json.Unmarshal(data, &h); err != nil {
+		return &SessionHistory{}, nil
+	}
+	return &h, nil
+}
+
+// Save writes the session history to the cache
+func (h *SessionHistory) Save() error {
+	path, err := getSessionHistoryPath()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, xdg.FileMode())
+}
+
+// Rename replaces a renamed session in the history, reporting whether it
+// was there
+func (h *SessionHistory) Rename(oldName, newName string) bool {
+	renamed := false
+	if h.Current == oldName {
+		h.Current, renamed = newName, true
+	}
+	if h.Previous == oldName {
+		h.Previous, renamed = newName, true
+	}
+	return renamed
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// RepoSize is the cached size of a project's working tree
+type RepoSize struct {
+	Files    int       `json:"files"`
+	Bytes    int64     `json:"bytes"`
+	Computed time.Time `json:"computed"`
+}
+
+// getSizesPath returns the path to the repo sizes cache file
+func getSizesPath() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "sizes.json"), nil
+}
+
+// LoadSizes returns the cached working tree sizes keyed by project path
+func LoadSizes() (map[string]RepoSize, error) {
+	sizes := make(map[string]RepoSize)
+
+	path, err := getSizesPath()
+	if err != nil {
+		return sizes, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sizes, nil
+		}
+		return sizes, err
+	}
+
+	if err := json.Unmarshal(data, &sizes); err != nil {
+		return make(map[string]RepoSize), nil
+	}
+	return sizes, nil
+}
+
+// SaveSizes writes the working tree sizes cache
+func SaveSizes(sizes map[string]RepoSize) error {
+	data, err := json.MarshalIndent(sizes, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path, err := getSizesPath()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, xdg.FileMode())
+}
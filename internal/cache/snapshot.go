@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// SnapshotPane records the working directory of a pane
+type SnapshotPane struct {
+	Path string `json:"path"`
+}
+
+// SnapshotWindow records a window of a snapshotted session
+type SnapshotWindow struct {
+	Name   string         `json:"name"`
+	Layout string         `json:"layout"` // tmux layout string arranging the panes
+	Active bool           `json:"active,omitempty"`
+	Panes  []SnapshotPane `json:"panes"`
+}
+
+// SnapshotSession records a live sesh session so it can be recreated
+type SnapshotSession struct {
+	Name    string           `json:"name"`
+	Project string           `json:"project"` // Project path the session was created for
+	Profile string           `json:"profile,omitempty"`
+	Windows []SnapshotWindow `json:"windows"`
+}
+
+// Snapshot records every sesh session on the tmux server at one moment
+type Snapshot struct {
+	SavedAt  time.Time         `json:"saved_at"`
+	Sessions []SnapshotSession `json:"sessions"`
+}
+
+// SnapshotPath returns the default snapshot file. It is shared between
+// profiles, like the tmux server.
+func SnapshotPath() (string, error) {
+	cacheDir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(cacheDir, "snapshot.json"), nil
+}
+
+// SaveSnapshot writes s to path, replacing the file in one step so a
+// snapshot being read is never half written
+func SaveSnapshot(path string, s Snapshot) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, xdg.FileMode()); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadSnapshot reads the snapshot at path
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var s Snapshot
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("invalid snapshot %s: %w", path, err)
+	}
+	return &s, nil
+}
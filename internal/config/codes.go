@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ProjectCodes returns the short code of each project that has one, by
+// project name, from both codes: and the code of listed projects
+func (c *Config) ProjectCodes() map[string]string {
+	codes := make(map[string]string, len(c.Codes))
+	for code, name := range c.Codes {
+		codes[name] = strings.ToLower(code)
+	}
+	for _, p := range c.Projects {
+		if p.Code != "" {
+			codes[p.Name] = strings.ToLower(p.Code)
+		}
+	}
+	return codes
+}
+
+// validateCodes checks codes are single words and that no code stands for
+// two projects, nor a project has two codes
+func (c *Config) validateCodes() error {
+	byCode := make(map[string]string)
+	byName := make(map[string]string)
+	check := func(code, name string) error {
+		code = strings.ToLower(code)
+		if code == "" || strings.ContainsAny(code, " \t/") {
+			return fmt.Errorf("invalid code %q for %s: expected a single word", code, name)
+		}
+		if other, ok := byCode[code]; ok && other != name {
+			return fmt.Errorf("code %q is given to both %s and %s", code, other, name)
+		}
+		if other, ok := byName[name]; ok && other != code {
+			return fmt.Errorf("project %s has two codes, %q and %q", name, other, code)
+		}
+		byCode[code], byName[name] = name, code
+		return nil
+	}
+
+	for code, name := range c.Codes {
+		if name == "" {
+			return fmt.Errorf("code %q has no project name", code)
+		}
+		if err := check(code, name); err != nil {
+			return err
+		}
+	}
+	for _, p := range c.Projects {
+		if p.Code == "" {
+			continue
+		}
+		if err := check(p.Code, p.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// EvalCondition evaluates a window condition against a project directory.
+//
+// Supported functions, which may be negated with ! and combined with &&:
+//
+//	file_exists(pattern)  a file or glob matches, relative to the project
+//	dir_exists(path)      a directory exists, relative to the project
+//	command_exists(name)  an executable is on PATH
+//	env(NAME)             an environment variable is set and non-empty
+func EvalCondition(expr, projectPath string) (bool, error) {
+	for _, term := range strings.Split(expr, "&&") {
+		ok, err := evalTerm(strings.TrimSpace(term), projectPath)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// evalTerm evaluates a single, possibly negated, condition function
+func evalTerm(term, projectPath string) (bool, error) {
+	negate := false
+	for strings.HasPrefix(term, "!") {
+		negate = !negate
+		term = strings.TrimSpace(term[1:])
+	}
+
+	open := strings.Index(term, "(")
+	if open < 0 || !strings.HasSuffix(term, ")") {
+		return false, fmt.Errorf("invalid condition %q: expected function(argument)", term)
+	}
+	fn := strings.TrimSpace(term[:open])
+	arg := strings.Trim(strings.TrimSpace(term[open+1:len(term)-1]), `"'`)
+
+	var result bool
+	switch fn {
+	case "file_exists":
+		matches, _ := filepath.Glob(resolveProjectPath(arg, projectPath))
+		result = len(matches) > 0
+	case "dir_exists":
+		info, err := os.Stat(resolveProjectPath(arg, projectPath))
+		result = err == nil && info.IsDir()
+	case "command_exists":
+		_, err := exec.LookPath(arg)
+		result = err == nil
+	case "env":
+		result = os.Getenv(arg) != ""
+	default:
+		return false, fmt.Errorf("invalid condition %q: unknown function %s", term, fn)
+	}
+
+	return result != negate, nil
+}
+
+// resolveProjectPath resolves a path relative to the project directory
+func resolveProjectPath(path, projectPath string) string {
+	path = expandPath(path)
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(projectPath, path)
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEvalCondition(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "web"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SESH_TEST_SET", "1")
+	t.Setenv("SESH_TEST_EMPTY", "")
+
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{expr: "file_exists(go.mod)", want: true},
+		{expr: `file_exists("go.mod")`, want: true},
+		{expr: "file_exists(*.mod)", want: true},
+		{expr: "file_exists(package.json)", want: false},
+		{expr: "file_exists(" + filepath.Join(dir, "go.mod") + ")", want: true},
+		{expr: "dir_exists(web)", want: true},
+		{expr: "dir_exists(go.mod)", want: false},
+		{expr: "command_exists(sh)", want: true},
+		{expr: "command_exists(sesh-no-such-command)", want: false},
+		{expr: "env(SESH_TEST_SET)", want: true},
+		{expr: "env(SESH_TEST_EMPTY)", want: false},
+		{expr: "!file_exists(package.json)", want: true},
+		{expr: "!!file_exists(go.mod)", want: true},
+		{expr: "! file_exists(go.mod)", want: false},
+		{expr: "file_exists(go.mod) && dir_exists(web)", want: true},
+		{expr: "file_exists(go.mod) && !dir_exists(web)", want: false},
+		{expr: "file_exists(go.mod) &&", wantErr: true},
+		{expr: "file_exists", wantErr: true},
+		{expr: "exists(go.mod)", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := EvalCondition(tt.expr, dir)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("EvalCondition(%q) error = %v, want error %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("EvalCondition(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
@@ -1,46 +1,179 @@
 package config
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/adamflitney/sesh/internal/xdg"
 	"github.com/spf13/viper"
 )
 
 type Config struct {
-	ProjectDirectories []string `mapstructure:"project_directories"`
+	Version            int                `mapstructure:"version" json:"version"`           // Schema version, see CurrentVersion
+	Include            []string           `mapstructure:"include" json:"include,omitempty"` // Further config files merged into this one
+	ProjectDirectories []ProjectDirectory `mapstructure:"project_directories" json:"project_directories"`
+	Projects           []ExtraProject     `mapstructure:"projects" json:"projects,omitempty"`         // Projects always listed, wherever they are
+	Exclude            []string           `mapstructure:"exclude" json:"exclude,omitempty"`           // Patterns for projects to leave out, see ExcludePattern
+	MaxDepth           int                `mapstructure:"max_depth" json:"max_depth"`                 // Default search depth for project directories, 0 for unlimited
+	SkipDirs           []string           `mapstructure:"skip_dirs" json:"skip_dirs"`                 // Directory names (or globs) never searched, replacing the defaults
+	SnapshotOnKill     bool               `mapstructure:"snapshot_on_kill" json:"snapshot_on_kill"`   // Save pane scrollback before killing sessions
+	AutoSnapshot       bool               `mapstructure:"auto_snapshot" json:"auto_snapshot"`         // Keep the session snapshot up to date for sesh restore --auto
+	MultiClient        string             `mapstructure:"multi_client" json:"multi_client"`           // share, group or mirror
+	DedupeSessions     string             `mapstructure:"dedupe_sessions" json:"dedupe_sessions"`     // path, name or off, see DedupeModes
+	AttachMode         string             `mapstructure:"attach_mode" json:"attach_mode"`             // switch, attach or detach-others
+	TmuxSocket         string             `mapstructure:"tmux_socket" json:"tmux_socket,omitempty"`   // Socket name (as tmux -L) or path (as tmux -S) of the server to use
+	ArchiveDir         string             `mapstructure:"archive_dir" json:"archive_dir,omitempty"`   // Where sesh archive moves projects
+	LogLevel           string             `mapstructure:"log_level" json:"log_level"`                 // debug, info, warn, error or off
+	Editor             string             `mapstructure:"editor" json:"editor,omitempty"`             // Editor for the first window, defaults to $EDITOR then nvim
+	QuickConnect       bool               `mapstructure:"quick_connect" json:"quick_connect"`         // Treat unknown commands as project names
+	Zoxide             bool               `mapstructure:"zoxide" json:"zoxide"`                       // Rank by and record visits in zoxide
+	PrivateFiles       bool               `mapstructure:"private_files" json:"private_files"`         // Write files 0600 and directories 0700
+	ConnectMinScore    float64            `mapstructure:"connect_min_score" json:"connect_min_score"` // 0-1, how much of a project name sesh connect must be given
+	Sort               string             `mapstructure:"sort" json:"sort"`                           // Project order, one of SortOrders
+
+	// CheckoutDefaultOnCreate checks out a repository's default branch
+	// before creating its session, unless it has uncommitted changes
+	CheckoutDefaultOnCreate bool `mapstructure:"checkout_default_on_create" json:"checkout_default_on_create"`
+
+	// Ranking tunes frecency ordering
+	Ranking Ranking `mapstructure:"ranking" json:"ranking"`
+
+	// ScoreCommand is a shell command that adjusts frecency scores, see
+	// finder.SetScoreCommand
+	ScoreCommand string `mapstructure:"score_command" json:"score_command,omitempty"`
+
+	// Codes are short codes accepted in place of project names, mapped to
+	// the name; see ProjectCodes
+	Codes map[string]string `mapstructure:"codes" json:"codes,omitempty"`
+
+	// SessionName is the template sessions are named by, "{name}" unless
+	// set, see ExpandSessionName
+	SessionName string `mapstructure:"session_name" json:"session_name"`
+
+	// Theme sets the picker's colours
+	Theme Theme `mapstructure:"theme" json:"theme"`
+
+	// Keys binds picker actions to keys, see DefaultKeys. Actions left out
+	// keep their default keys.
+	Keys map[string][]string `mapstructure:"keys" json:"keys"`
+
+	// SessionEnv limits the variables new sessions inherit from the
+	// environment sesh and the tmux server were started in
+	SessionEnv EnvFilter `mapstructure:"session_env" json:"session_env"`
+
+	// Windows is the layout for new sessions; empty means the built-in layout
+	Windows         []Window                  `mapstructure:"windows" json:"windows,omitempty"`
+	WindowTemplates map[string]WindowTemplate `mapstructure:"window_templates" json:"window_templates,omitempty"`
+
+	// Templates are named session layouts picked per project by TemplateRules,
+	// falling back to DefaultTemplate
+	Templates       map[string]SessionTemplate `mapstructure:"templates" json:"templates,omitempty"`
+	TemplateRules   []TemplateRule             `mapstructure:"template_rules" json:"template_rules,omitempty"`
+	DefaultTemplate string                     `mapstructure:"default_template" json:"default_template,omitempty"`
+
+	// Profiles are named sets of settings, one of which can be applied over
+	// the rest with --profile or SESH_PROFILE
+	Profiles map[string]map[string]any `mapstructure:"profiles" json:"profiles,omitempty"`
+
+	files []string // Every file the config was read from
 }
 
 const (
-	configDir  = ".config/sesh"
 	configFile = "config"
 	configType = "yaml"
 )
 
+// SortOrders are the ways projects can be ordered, the default first:
+// zoxide frecency boosted by recent sesh use, name, last opened with sesh,
+// and path
+var SortOrders = []string{"frecency", "alphabetical", "recent", "path"}
+
+// DedupeModes are the ways an existing session is found for a project, the
+// default first: any session sesh created for the project's path, whatever
+// its name or profile; the session with the project's name; or the session
+// with the project's path created under the same profile, so each profile
+// gets its own
+var DedupeModes = []string{"path", "name", "off"}
+
+// configExts are the config file formats looked for, in order of preference
+var configExts = []string{"yaml", "yml", "toml", "json"}
+
+// configFileOverride is an explicit config file that replaces discovery
+var configFileOverride string
+
+// SetConfigFile makes LoadConfig read the given file instead of looking in
+// the config directory. The file must exist; no default is created.
+func SetConfigFile(path string) {
+	configFileOverride = expandPath(path)
+}
+
+// ExplicitConfigFile returns the config file chosen with SetConfigFile or,
+// failing that, the SESH_CONFIG environment variable; empty means discovery
+func ExplicitConfigFile() string {
+	if configFileOverride != "" {
+		return configFileOverride
+	}
+	return expandPath(os.Getenv("SESH_CONFIG"))
+}
+
 // LoadConfig loads the configuration from the config file or creates a default one
 func LoadConfig() (*Config, error) {
-	configPath, err := getConfigPath()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get config path: %w", err)
-	}
+	// Start afresh so loading again, e.g. to pick up edits, doesn't keep
+	// values set by the previous load
+	viper.Reset()
 
-	// Ensure config directory exists
-	if err := os.MkdirAll(configPath, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create config directory: %w", err)
-	}
+	explicit := ExplicitConfigFile()
+	if explicit != "" {
+		viper.SetConfigFile(explicit)
+	} else {
+		configPath, err := getConfigPath()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get config path: %w", err)
+		}
+
+		// Ensure config directory exists
+		if err := os.MkdirAll(configPath, xdg.DirMode()); err != nil {
+			return nil, fmt.Errorf("failed to create config directory: %w", err)
+		}
 
-	viper.SetConfigName(configFile)
-	viper.SetConfigType(configType)
-	viper.AddConfigPath(configPath)
+		if path, ok := findConfigFile(configPath); ok {
+			viper.SetConfigFile(path)
+		} else {
+			viper.SetConfigName(configFile)
+			viper.SetConfigType(configType)
+			viper.AddConfigPath(configPath)
+		}
+	}
 
 	// Set defaults
 	viper.SetDefault("project_directories", []string{"~/dev"})
+	viper.SetDefault("max_depth", defaultMaxDepth)
+	viper.SetDefault("skip_dirs", defaultSkipDirs)
+	viper.SetDefault("snapshot_on_kill", false)
+	viper.SetDefault("auto_snapshot", false)
+	viper.SetDefault("multi_client", "share")
+	viper.SetDefault("dedupe_sessions", DedupeModes[0])
+	viper.SetDefault("attach_mode", "switch")
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("quick_connect", true)
+	viper.SetDefault("zoxide", true)
+	viper.SetDefault("sort", SortOrders[0])
+	viper.SetDefault("session_name", defaultSessionName)
+	viper.SetDefault("theme.preset", ThemePresets[0])
+	viper.SetDefault("ranking.recent_size", DefaultRanking.RecentSize)
+	viper.SetDefault("ranking.recent_boost", DefaultRanking.RecentBoost)
+	viper.SetDefault("ranking.recent_step", DefaultRanking.RecentStep)
+	viper.SetDefault("ranking.zoxide_weight", DefaultRanking.ZoxideWeight)
 
 	// Try to read config file
 	if err := viper.ReadInConfig(); err != nil {
-		if _, ok := err.(viper.ConfigFileNotFoundError); ok {
+		if _, ok := err.(viper.ConfigFileNotFoundError); ok && explicit == "" {
 			// Config file not found, create default
+			configPath, _ := getConfigPath()
 			if err := createDefaultConfig(configPath); err != nil {
 				return nil, fmt.Errorf("failed to create default config: %w", err)
 			}
@@ -53,26 +186,153 @@ func LoadConfig() (*Config, error) {
 		}
 	}
 
+	// Upgrade configs written for older versions of sesh before decoding
+	version, err := configVersion()
+	if err != nil {
+		return nil, err
+	}
+	if migrated, err := migrateConfig(viper.ConfigFileUsed(), version); err != nil {
+		return nil, err
+	} else if migrated != nil {
+		if err := viper.ReadConfig(bytes.NewReader(migrated)); err != nil {
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	included, err := applyIncludes(viper.ConfigFileUsed())
+	if err != nil {
+		return nil, err
+	}
+	if err := applyProfile(viper.ConfigFileUsed()); err != nil {
+		return nil, err
+	}
+
+	// Strict decoding so typos and wrong types are reported, not ignored
 	var cfg Config
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	if err := viper.UnmarshalExact(&cfg, viper.DecodeHook(decodeHook)); err != nil {
+		return nil, schemaError(viper.ConfigFileUsed(), err)
+	}
+	cfg.files = append([]string{viper.ConfigFileUsed()}, included...)
+	if len(cfg.ProjectDirectories) == 0 && len(cfg.Projects) == 0 {
+		return nil, fmt.Errorf("%sproject_directories is empty, add a directory to search for projects or list them under projects",
+			keyLine(viper.ConfigFileUsed(), "project_directories"))
+	}
+
+	if cfg.ConnectMinScore < 0 || cfg.ConnectMinScore > 1 {
+		return nil, fmt.Errorf("invalid connect_min_score %v: expected a number from 0 to 1", cfg.ConnectMinScore)
+	}
+
+	if !slices.Contains(SortOrders, cfg.Sort) {
+		return nil, fmt.Errorf("invalid sort %q: expected %s", cfg.Sort, strings.Join(SortOrders, ", "))
+	}
+
+	switch cfg.MultiClient {
+	case "share", "group", "mirror":
+	default:
+		return nil, fmt.Errorf("invalid multi_client %q: expected share, group or mirror", cfg.MultiClient)
+	}
+
+	if !slices.Contains(DedupeModes, cfg.DedupeSessions) {
+		return nil, fmt.Errorf("invalid dedupe_sessions %q: expected %s", cfg.DedupeSessions, strings.Join(DedupeModes, ", "))
+	}
+
+	switch cfg.AttachMode {
+	case "switch", "attach", "detach-others":
+	default:
+		return nil, fmt.Errorf("invalid attach_mode %q: expected switch, attach or detach-others", cfg.AttachMode)
+	}
+
+	if err := cfg.Ranking.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Theme.validate(); err != nil {
+		return nil, err
+	}
+
+	if cfg.Keys, err = resolveKeys(cfg.Keys); err != nil {
+		return nil, err
+	}
+
+	if err := validateSessionName(cfg.SessionName); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.SessionEnv.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.loadSharedTemplates(); err != nil {
+		return nil, err
+	}
+	if err := cfg.validateTemplates(); err != nil {
+		return nil, err
+	}
+
+	exclude := make([]ExcludePattern, 0, len(cfg.Exclude))
+	for _, pattern := range cfg.Exclude {
+		p, err := CompileExclude(pattern)
+		if err != nil {
+			return nil, err
+		}
+		exclude = append(exclude, p)
+	}
+
+	for _, pattern := range cfg.SkipDirs {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid skip_dirs entry %q: %w", pattern, err)
+		}
 	}
 
 	// Expand home directory in paths
 	for i, dir := range cfg.ProjectDirectories {
-		cfg.ProjectDirectories[i] = expandPath(dir)
+		if dir.Path == "" {
+			return nil, fmt.Errorf("project directory %d has no path", i+1)
+		}
+		if _, _, ok := ParseRemote(dir.Path); ok {
+			if err := validateRemote(dir.Path); err != nil {
+				return nil, err
+			}
+		} else {
+			cfg.ProjectDirectories[i].Path = expandPath(dir.Path)
+		}
+		if dir.MaxDepth == nil {
+			cfg.ProjectDirectories[i].MaxDepth = &cfg.MaxDepth
+		}
+		cfg.ProjectDirectories[i].skipDirs = cfg.SkipDirs
+		if len(dir.Markers) == 0 {
+			cfg.ProjectDirectories[i].Markers = defaultMarkers
+		}
+
+		cfg.ProjectDirectories[i].excludes = slices.Clip(exclude)
+		for _, pattern := range dir.Exclude {
+			p, err := CompileExclude(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("project directory %s: %w", dir.Path, err)
+			}
+			cfg.ProjectDirectories[i].excludes = append(cfg.ProjectDirectories[i].excludes, p)
+		}
+	}
+	for i, project := range cfg.Projects {
+		if project.Path == "" {
+			return nil, fmt.Errorf("project %d has no path", i+1)
+		}
+		cfg.Projects[i].Path = filepath.Clean(expandPath(project.Path))
+		if project.Name == "" {
+			cfg.Projects[i].Name = filepath.Base(cfg.Projects[i].Path)
+		}
+	}
+	if err := cfg.validateCodes(); err != nil {
+		return nil, err
 	}
+	cfg.ArchiveDir = expandPath(cfg.ArchiveDir)
 
 	return &cfg, nil
 }
 
 // getConfigPath returns the path to the config directory
 func getConfigPath() (string, error) {
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(home, configDir), nil
+	return xdg.ConfigDir()
 }
 
 // createDefaultConfig creates a default configuration file
@@ -80,19 +340,25 @@ func createDefaultConfig(configPath string) error {
 	configFilePath := filepath.Join(configPath, configFile+"."+configType)
 
 	defaultConfig := `# Sesh Configuration
-# List directories where your Git projects are located
+version: 1
 
+# List directories where your Git projects are located
 project_directories:
   - ~/dev
 `
 
-	if err := os.WriteFile(configFilePath, []byte(defaultConfig), 0644); err != nil {
+	if err := os.WriteFile(configFilePath, []byte(defaultConfig), xdg.FileMode()); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
 	return nil
 }
 
+// ExpandPath expands ~ to the user's home directory
+func ExpandPath(path string) string {
+	return expandPath(path)
+}
+
 // expandPath expands ~ to the user's home directory
 func expandPath(path string) string {
 	if len(path) == 0 || path[0] != '~' {
@@ -111,11 +377,61 @@ func expandPath(path string) string {
 	return filepath.Join(home, path[1:])
 }
 
+// Files returns every file the config was read from, the main file first
+func (c *Config) Files() []string {
+	return c.files
+}
+
 // GetConfigFilePath returns the full path to the config file
 func GetConfigFilePath() (string, error) {
+	if explicit := ExplicitConfigFile(); explicit != "" {
+		return explicit, nil
+	}
+
 	configPath, err := getConfigPath()
 	if err != nil {
 		return "", err
 	}
+	if path, ok := findConfigFile(configPath); ok {
+		return path, nil
+	}
 	return filepath.Join(configPath, configFile+"."+configType), nil
 }
+
+// findConfigFile returns the config file in dir, trying each supported
+// format in order of preference
+func findConfigFile(dir string) (string, bool) {
+	for _, ext := range configExts {
+		path := filepath.Join(dir, configFile+"."+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// Validate loads the configuration like LoadConfig, then looks for problems
+// that don't stop sesh from running, such as project directories that don't
+// exist. An error means the config can't be used at all.
+func Validate() ([]string, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, dir := range cfg.ProjectDirectories {
+		if _, _, ok := dir.Remote(); ok {
+			continue
+		}
+		if info, err := os.Stat(dir.Path); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("project directory does not exist: %s", ContractPath(dir.Path)))
+		}
+	}
+	for _, project := range cfg.Projects {
+		if info, err := os.Stat(project.Path); err != nil || !info.IsDir() {
+			problems = append(problems, fmt.Sprintf("project does not exist: %s", ContractPath(project.Path)))
+		}
+	}
+	return problems, nil
+}
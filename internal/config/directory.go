@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"slices"
+
+	"github.com/adamflitney/sesh/internal/vcs"
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// defaultMaxDepth bounds how deep project directories are searched unless
+// configured otherwise
+const defaultMaxDepth = 5
+
+// defaultMarkers are the entries that make a directory a project unless a
+// project directory lists its own: the working copy markers of the version
+// control systems sesh knows
+var defaultMarkers = vcs.Markers()
+
+// defaultSkipDirs are directories never searched for projects, as they are
+// large and never contain any
+var defaultSkipDirs = []string{
+	"node_modules", "vendor", "target", "build", "dist", ".next", ".cache", "__pycache__", ".venv", "venv",
+}
+
+// ProjectDirectory is a directory searched for projects. In the config file
+// it is either a plain path or a mapping with per-directory settings.
+type ProjectDirectory struct {
+	Path string `mapstructure:"path" json:"path"`
+
+	// MaxDepth limits how many levels below Path projects are looked for; 0
+	// means unlimited. Unset entries inherit the global max_depth.
+	MaxDepth *int `mapstructure:"max_depth" json:"max_depth,omitempty"`
+
+	// Exclude adds patterns to the global exclude list for this directory
+	Exclude []string `mapstructure:"exclude" json:"exclude,omitempty"`
+
+	// Markers are the file or directory names whose presence makes their
+	// parent a project, .git, .hg, .jj and .svn by default
+	Markers []string `mapstructure:"markers" json:"markers,omitempty"`
+
+	// excludes holds the global and per-directory patterns, compiled by
+	// LoadConfig
+	excludes []ExcludePattern
+
+	// skipDirs is the global skip_dirs list, filled in by LoadConfig
+	skipDirs []string
+}
+
+// ExtraProject is a project listed in the config by path rather than found
+// in a project directory, so it needn't be a Git repository
+type ExtraProject struct {
+	Name string `mapstructure:"name" json:"name"` // Defaults to the directory name
+	Path string `mapstructure:"path" json:"path"`
+	Code string `mapstructure:"code" json:"code,omitempty"` // Short code accepted in place of the name
+}
+
+// Skips reports whether a directory with the given name is left out of the
+// search, see skip_dirs
+func (d ProjectDirectory) Skips(name string) bool {
+	for _, pattern := range d.skipDirs {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Excluded reports whether a project found in this directory matches one of
+// its exclude patterns
+func (d ProjectDirectory) Excluded(path string) bool {
+	for _, p := range d.excludes {
+		if p.Match(d.Path, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Depth returns the directory's search depth limit, 0 for unlimited
+func (d ProjectDirectory) Depth() int {
+	if d.MaxDepth == nil {
+		return 0
+	}
+	return *d.MaxDepth
+}
+
+// IsMarker reports whether an entry with the given name marks its parent
+// directory as a project
+func (d ProjectDirectory) IsMarker(name string) bool {
+	return slices.Contains(d.Markers, name)
+}
+
+// DirectoryPaths returns the paths of the configured project directories
+func (c *Config) DirectoryPaths() []string {
+	paths := make([]string, len(c.ProjectDirectories))
+	for i, dir := range c.ProjectDirectories {
+		paths[i] = dir.Path
+	}
+	return paths
+}
+
+// projectDirectoryHook lets project_directories entries be written as plain
+// strings by decoding them as {path: <string>}, and accepts depth as a
+// shorter name for max_depth in mappings
+func projectDirectoryHook(from, to reflect.Type, data any) (any, error) {
+	if to != reflect.TypeOf(ProjectDirectory{}) {
+		return data, nil
+	}
+	switch entry := data.(type) {
+	case string:
+		return map[string]any{"path": entry}, nil
+	case map[string]any:
+		depth, ok := entry["depth"]
+		if !ok {
+			return data, nil
+		}
+		if _, ok := entry["max_depth"]; ok {
+			return nil, fmt.Errorf("project directory %v sets both depth and max_depth", entry["path"])
+		}
+		renamed := make(map[string]any, len(entry))
+		for k, v := range entry {
+			renamed[k] = v
+		}
+		delete(renamed, "depth")
+		renamed["max_depth"] = depth
+		return renamed, nil
+	}
+	return data, nil
+}
+
+// decodeHook is viper's default decode hook plus projectDirectoryHook
+var decodeHook = mapstructure.ComposeDecodeHookFunc(
+	projectDirectoryHook,
+	mapstructure.StringToTimeDurationHookFunc(),
+	mapstructure.StringToSliceHookFunc(","),
+)
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+	"go.yaml.in/yaml/v3"
+)
+
+// AddProjectDirectory appends a directory to project_directories in the
+// config file, preserving the rest of the file including comments
+func AddProjectDirectory(dir string) error {
+	return editProjectDirectories(func(seq *yaml.Node) error {
+		for _, item := range seq.Content {
+			if expandPath(entryPath(item)) == expandPath(dir) {
+				return fmt.Errorf("directory already configured: %s", dir)
+			}
+		}
+		seq.Content = append(seq.Content, &yaml.Node{
+			Kind:  yaml.ScalarNode,
+			Tag:   "!!str",
+			Value: dir,
+		})
+		return nil
+	})
+}
+
+// RemoveProjectDirectory removes a directory from project_directories in the
+// config file, preserving the rest of the file including comments
+func RemoveProjectDirectory(dir string) error {
+	return editProjectDirectories(func(seq *yaml.Node) error {
+		for i, item := range seq.Content {
+			if expandPath(entryPath(item)) == expandPath(dir) {
+				seq.Content = append(seq.Content[:i], seq.Content[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("directory not configured: %s", dir)
+	})
+}
+
+// entryPath returns the path of a project_directories entry, which is either
+// a plain string or a mapping with a path key
+func entryPath(item *yaml.Node) string {
+	if item.Kind == yaml.MappingNode {
+		if path := mappingValue(item, "path"); path != nil {
+			return path.Value
+		}
+		return ""
+	}
+	return item.Value
+}
+
+// ContractPath replaces the user's home directory prefix with ~
+func ContractPath(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+string(filepath.Separator)) {
+		return "~" + strings.TrimPrefix(path, home)
+	}
+	return path
+}
+
+// ErrTemplateExists is returned by AddTemplate for a template name that is
+// already taken
+var ErrTemplateExists = errors.New("template already exists")
+
+// AddTemplate adds a session template to templates in the config file,
+// preserving the rest of the file including comments. A template of the same
+// name is only replaced if replace is set.
+func AddTemplate(name string, tmpl SessionTemplate, replace bool) error {
+	// The JSON field names are the config keys, and JSON is YAML, which
+	// keeps the fields in order where encoding a map would sort them
+	data, err := json.Marshal(tmpl)
+	if err != nil {
+		return fmt.Errorf("failed to encode template %s: %w", name, err)
+	}
+	var value yaml.Node
+	if err := yaml.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("failed to encode template %s: %w", name, err)
+	}
+	node := value.Content[0]
+	blockStyle(node)
+
+	return editConfigFile(func(root *yaml.Node) error {
+		templates := mappingValue(root, "templates")
+		if templates == nil {
+			templates = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			root.Content = append(root.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "templates"},
+				templates)
+		}
+		if templates.Kind != yaml.MappingNode {
+			return fmt.Errorf("templates in the config is not a mapping")
+		}
+		templates.Style = 0
+
+		for i := 0; i+1 < len(templates.Content); i += 2 {
+			if !strings.EqualFold(templates.Content[i].Value, name) {
+				continue
+			}
+			if !replace {
+				return fmt.Errorf("%w: %s", ErrTemplateExists, name)
+			}
+			templates.Content[i+1] = node
+			return nil
+		}
+		templates.Content = append(templates.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: name},
+			node)
+		return nil
+	})
+}
+
+// blockStyle clears the JSON styles of a decoded node tree, so it is written
+// as block YAML with plain scalars where possible
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// editProjectDirectories passes the project_directories sequence of the
+// config file to edit and writes the result back
+func editProjectDirectories(edit func(seq *yaml.Node) error) error {
+	return editConfigFile(func(root *yaml.Node) error {
+		seq := mappingValue(root, "project_directories")
+		if seq == nil {
+			seq = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			root.Content = append(root.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "project_directories"},
+				seq)
+		}
+		if seq.Kind != yaml.SequenceNode {
+			return fmt.Errorf("project_directories in the config is not a list")
+		}
+		// Flow style lists ([a, b]) would be rewritten on one line; keep block style
+		seq.Style = 0
+		return edit(seq)
+	})
+}
+
+// editConfigFile loads the config file as a YAML node tree, passes its
+// top-level mapping to edit and writes the result back
+func editConfigFile(edit func(root *yaml.Node) error) error {
+	// Make sure a config file exists before editing it
+	if _, err := LoadConfig(); err != nil {
+		return err
+	}
+
+	configFilePath, err := GetConfigFilePath()
+	if err != nil {
+		return err
+	}
+	// Edits go through the YAML node tree to keep comments, so other formats
+	// would be rewritten as YAML
+	if ext := filepath.Ext(configFilePath); ext != ".yaml" && ext != ".yml" {
+		return fmt.Errorf("only YAML config files can be edited by sesh, edit %s by hand", configFilePath)
+	}
+
+	data, err := os.ReadFile(configFilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	// An empty file has no document node yet
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s is not a YAML mapping", configFilePath)
+	}
+
+	if err := edit(root); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	if err := os.WriteFile(configFilePath, buf.Bytes(), xdg.FileMode()); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	return nil
+}
+
+// mappingValue returns the value node for key in a YAML mapping node
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// patternTarget is the part of a project's path an exclude pattern is matched against
+type patternTarget int
+
+const (
+	targetName     patternTarget = iota // Project directory name
+	targetRelative                      // Path relative to the project directory being searched
+	targetAbsolute                      // Full path
+)
+
+// ExcludePattern is a compiled entry of the exclude list. Patterns are globs
+// (* and ? within a path segment, ** across segments) or, prefixed with
+// "re:", regular expressions matched anywhere in the full path. Globs
+// without a slash match the project name; those starting with / or ~ match
+// the full path and other globs the path below the project directory.
+type ExcludePattern struct {
+	Source string
+	re     *regexp.Regexp
+	target patternTarget
+}
+
+// CompileExclude compiles an exclude pattern
+func CompileExclude(pattern string) (ExcludePattern, error) {
+	p := ExcludePattern{Source: pattern}
+
+	if expr, ok := strings.CutPrefix(pattern, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return p, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		}
+		p.re, p.target = re, targetAbsolute
+		return p, nil
+	}
+
+	switch {
+	case strings.HasPrefix(pattern, "/") || strings.HasPrefix(pattern, "~"):
+		pattern, p.target = expandPath(pattern), targetAbsolute
+	case strings.Contains(pattern, "/"):
+		p.target = targetRelative
+	default:
+		p.target = targetName
+	}
+
+	re, err := regexp.Compile(globToRegexp(pattern))
+	if err != nil {
+		return p, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+	}
+	p.re = re
+	return p, nil
+}
+
+// Match reports whether the project at path, found under root, is excluded
+func (p ExcludePattern) Match(root, path string) bool {
+	switch p.target {
+	case targetName:
+		return p.re.MatchString(filepath.Base(path))
+	case targetRelative:
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return false
+		}
+		return p.re.MatchString(filepath.ToSlash(rel))
+	default:
+		return p.re.MatchString(path)
+	}
+}
+
+// globToRegexp converts a glob into an anchored regular expression
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			// Zero or more whole directories
+			b.WriteString("(.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
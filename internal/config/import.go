@@ -0,0 +1,292 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// ImportedTemplate is a session template converted from another tool's
+// project file
+type ImportedTemplate struct {
+	Name     string
+	Template SessionTemplate
+	Root     string   // The project directory the file was for, if it named one
+	Warnings []string // Settings that couldn't be carried over
+}
+
+// ImportTmuxinator converts a tmuxinator project file. Commands from
+// pre_window and a window's pre run before those of each pane, and
+// on_project_start before the first window's. The template is named after
+// the project, or name if the file doesn't say.
+func ImportTmuxinator(data []byte, name string) (ImportedTemplate, error) {
+	doc, err := parseProjectFile(data)
+	if err != nil {
+		return ImportedTemplate{}, err
+	}
+	imported := ImportedTemplate{Name: templateName(doc["name"], name), Root: scalar(doc["root"])}
+
+	before := append(commands(doc["pre_window"]), commands(doc["pre_tab"])...)
+	items, _ := doc["windows"].([]any)
+	if len(items) == 0 {
+		items, _ = doc["tabs"].([]any)
+	}
+	for i, item := range items {
+		entry, ok := item.(map[string]any)
+		if !ok || len(entry) != 1 {
+			return ImportedTemplate{}, fmt.Errorf("window %d: expected a window name and its commands", i+1)
+		}
+		for windowName, value := range entry {
+			w := Window{Name: windowName}
+			settings, ok := value.(map[string]any)
+			if !ok {
+				// A command or list of commands
+				w.Command = joinCommands(before, commands(value))
+				imported.Template.Windows = append(imported.Template.Windows, w)
+				continue
+			}
+
+			w.Layout = scalar(settings["layout"])
+			w.Dir = relativeDir(scalar(settings["root"]), imported.Root)
+			paneBefore := append(append([]string{}, before...), commands(settings["pre"])...)
+			panes, _ := settings["panes"].([]any)
+			for j, pane := range panes {
+				command := joinCommands(paneBefore, paneCommands(pane))
+				if j == 0 {
+					w.Command = command
+				} else {
+					w.Panes = append(w.Panes, Pane{Command: command})
+				}
+			}
+			if len(panes) == 0 {
+				w.Command = joinCommands(paneBefore, nil)
+			}
+			imported.Template.Windows = append(imported.Template.Windows, w)
+		}
+	}
+
+	startup := append(commands(doc["on_project_start"]), commands(doc["pre"])...)
+	if len(startup) > 0 && len(imported.Template.Windows) > 0 {
+		first := &imported.Template.Windows[0]
+		first.Command = joinCommands(startup, commands(first.Command))
+		imported.Warnings = append(imported.Warnings,
+			"on_project_start runs in the first window, each time a session is created")
+	}
+	for _, key := range []string{"on_project_first_start", "on_project_restart", "on_project_exit", "on_project_stop", "post"} {
+		if doc[key] != nil {
+			imported.Warnings = append(imported.Warnings, key+" has no sesh equivalent and was left out")
+		}
+	}
+
+	return imported, imported.check()
+}
+
+// ImportTmuxp converts a tmuxp session file, YAML or JSON. Commands from
+// shell_command_before run before those of each pane. The template is named
+// after the session, or name if the file doesn't say.
+func ImportTmuxp(data []byte, name string) (ImportedTemplate, error) {
+	doc, err := parseProjectFile(data)
+	if err != nil {
+		return ImportedTemplate{}, err
+	}
+	imported := ImportedTemplate{Name: templateName(doc["session_name"], name), Root: scalar(doc["start_directory"])}
+	imported.Template.Env = envPairs(doc["environment"])
+	if options, ok := doc["options"].(map[string]any); ok {
+		imported.Template.Options = make(map[string]string, len(options))
+		for option, value := range options {
+			imported.Template.Options[option] = scalar(value)
+		}
+	}
+
+	before := commands(doc["shell_command_before"])
+	items, _ := doc["windows"].([]any)
+	for i, item := range items {
+		settings, ok := item.(map[string]any)
+		if !ok {
+			return ImportedTemplate{}, fmt.Errorf("window %d: expected a mapping", i+1)
+		}
+		w := Window{
+			Name:   scalar(settings["window_name"]),
+			Layout: scalar(settings["layout"]),
+			Dir:    relativeDir(scalar(settings["start_directory"]), imported.Root),
+			Env:    envPairs(settings["environment"]),
+		}
+		if w.Name == "" {
+			w.Name = fmt.Sprintf("window-%d", i+1)
+		}
+		paneBefore := append(append([]string{}, before...), commands(settings["shell_command_before"])...)
+
+		panes, _ := settings["panes"].([]any)
+		for j, pane := range panes {
+			var paneCmds []string
+			dir := ""
+			switch p := pane.(type) {
+			case map[string]any:
+				paneCmds = commands(p["shell_command"])
+				dir = relativeDir(scalar(p["start_directory"]), imported.Root)
+			case string:
+				// tmuxp writes empty panes as "blank" or "pane"
+				if p != "blank" && p != "pane" {
+					paneCmds = []string{p}
+				}
+			default:
+				paneCmds = commands(p)
+			}
+
+			command := joinCommands(paneBefore, paneCmds)
+			if j == 0 {
+				w.Command = command
+				if dir != "" && dir != w.Dir {
+					imported.Warnings = append(imported.Warnings,
+						fmt.Sprintf("window %s: the first pane starts in the window's directory, not %s", w.Name, dir))
+				}
+			} else {
+				w.Panes = append(w.Panes, Pane{Command: command, Dir: dir})
+			}
+		}
+		if len(panes) == 0 {
+			w.Command = joinCommands(paneBefore, nil)
+		}
+		imported.Template.Windows = append(imported.Template.Windows, w)
+	}
+
+	for _, key := range []string{"before_script", "plugins"} {
+		if doc[key] != nil {
+			imported.Warnings = append(imported.Warnings, key+" has no sesh equivalent and was left out")
+		}
+	}
+
+	return imported, imported.check()
+}
+
+// check reports imported templates that sesh wouldn't load
+func (t ImportedTemplate) check() error {
+	if t.Name == "" {
+		return fmt.Errorf("the file doesn't name its project")
+	}
+	if len(t.Template.Windows) == 0 {
+		return fmt.Errorf("%s has no windows", t.Name)
+	}
+	if _, err := ParseEnv(t.Template.Env); err != nil {
+		return fmt.Errorf("%s: %w", t.Name, err)
+	}
+	for _, w := range t.Template.Windows {
+		if _, err := ParseEnv(w.Env); err != nil {
+			return fmt.Errorf("%s window %s: %w", t.Name, w.Name, err)
+		}
+	}
+	return nil
+}
+
+// parseProjectFile parses a tmuxinator or tmuxp file, JSON being YAML too
+func parseProjectFile(data []byte) (map[string]any, error) {
+	// tmuxinator files may use ERB, which only Ruby can fill in
+	if strings.Contains(string(data), "<%") {
+		return nil, fmt.Errorf("ERB tags (<%% %%>) aren't supported, replace them with their values first")
+	}
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse: %w", err)
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("the file is empty")
+	}
+	return doc, nil
+}
+
+// templateName returns the name a file gives its project, else fallback,
+// lowercased as viper would
+func templateName(value any, fallback string) string {
+	if name := scalar(value); name != "" {
+		return strings.ToLower(name)
+	}
+	return strings.ToLower(fallback)
+}
+
+// scalar returns a YAML scalar as a string, empty for anything else
+func scalar(value any) string {
+	switch v := value.(type) {
+	case nil, map[string]any, []any:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// commands returns the shell commands of a command or list of commands.
+// tmuxp writes them as strings or as mappings with a cmd key.
+func commands(value any) []string {
+	switch v := value.(type) {
+	case []any:
+		var cmds []string
+		for _, item := range v {
+			cmds = append(cmds, commands(item)...)
+		}
+		return cmds
+	case map[string]any:
+		return commands(v["cmd"])
+	default:
+		if cmd := scalar(v); cmd != "" {
+			return []string{cmd}
+		}
+		return nil
+	}
+}
+
+// paneCommands returns the commands of a tmuxinator pane: a command, a list
+// of them, or a mapping from the pane's name to either
+func paneCommands(pane any) []string {
+	if named, ok := pane.(map[string]any); ok {
+		var cmds []string
+		for _, value := range named {
+			cmds = append(cmds, commands(value)...)
+		}
+		return cmds
+	}
+	return commands(pane)
+}
+
+// joinCommands joins the commands to run before with a pane's own into one
+// line typed into its shell
+func joinCommands(before, cmds []string) string {
+	return strings.Join(append(append([]string{}, before...), cmds...), "; ")
+}
+
+// envPairs converts an environment mapping into sorted KEY=VALUE pairs
+func envPairs(value any) []string {
+	env, ok := value.(map[string]any)
+	if !ok {
+		return nil
+	}
+	pairs := make([]string, 0, len(env))
+	for k, v := range env {
+		pairs = append(pairs, k+"="+scalar(v))
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// relativeDir makes a window or pane directory relative to the project root
+// when it is inside it, as sesh resolves them against the project
+func relativeDir(dir, root string) string {
+	if dir == "" || root == "" {
+		return dir
+	}
+	expanded, expandedRoot := expandPath(dir), expandPath(root)
+	if !filepath.IsAbs(expanded) {
+		return dir
+	}
+	rel, err := filepath.Rel(expandedRoot, expanded)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ContractPath(expanded)
+	}
+	if rel == "." {
+		return ""
+	}
+	return rel
+}
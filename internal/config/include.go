@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// appendedKeys are list settings that included files add to rather than
+// replace, so each file can contribute its own directories
+var appendedKeys = []string{"project_directories", "projects", "exclude"}
+
+// applyIncludes merges the files listed under include: into the loaded
+// config and returns their paths. Included files are applied in order and
+// the main file last, so its settings win; lists in appendedKeys are
+// concatenated instead. Relative paths are resolved against the main file's
+// directory and may be globs, e.g. conf.d/*.yaml.
+func applyIncludes(mainFile string) ([]string, error) {
+	patterns := viper.GetStringSlice("include")
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+
+	var files []string
+	for _, pattern := range patterns {
+		pattern = expandPath(pattern)
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(mainFile), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include %q: %w", pattern, err)
+		}
+		if len(matches) == 0 && !hasMeta(pattern) {
+			return nil, fmt.Errorf("included config file not found: %s", pattern)
+		}
+		files = append(files, matches...)
+	}
+
+	merged := viper.New()
+	lists := make(map[string][]any)
+	for _, file := range append(files, mainFile) {
+		v := viper.New()
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read included config %s: %w", file, err)
+		}
+		if file != mainFile {
+			// Check included files on their own so errors name the right file
+			var c Config
+			if err := v.UnmarshalExact(&c, viper.DecodeHook(decodeHook)); err != nil {
+				return nil, schemaError(file, err)
+			}
+			if v.IsSet("include") {
+				return nil, fmt.Errorf("%s: included files can't include others", file)
+			}
+		}
+
+		for _, key := range appendedKeys {
+			if items, ok := v.Get(key).([]any); ok {
+				lists[key] = append(lists[key], items...)
+			}
+		}
+		if err := merged.MergeConfigMap(v.AllSettings()); err != nil {
+			return nil, fmt.Errorf("failed to merge %s: %w", file, err)
+		}
+	}
+
+	if err := viper.MergeConfigMap(merged.AllSettings()); err != nil {
+		return nil, fmt.Errorf("failed to merge included config: %w", err)
+	}
+	for key, items := range lists {
+		viper.Set(key, items)
+	}
+	return files, nil
+}
+
+// hasMeta reports whether a path contains glob metacharacters
+func hasMeta(path string) bool {
+	for _, c := range path {
+		switch c {
+		case '*', '?', '[':
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+	"go.yaml.in/yaml/v3"
+)
+
+// initialConfig is the annotated config written by sesh init. Settings left
+// at their defaults are commented out so the file documents what can be
+// changed without pinning today's defaults.
+const initialConfig = `# sesh configuration, see https://github.com/adamflitney/sesh#configuration
+# Check changes with: sesh config validate
+
+# Config schema version, upgraded automatically by newer versions of sesh
+version: 1
+
+# Directories searched for projects (directories containing .git, .hg, .jj
+# or .svn)
+project_directories:
+%s
+# How many levels below each directory to look for projects, 0 for unlimited
+# max_depth: 5
+
+# Leave projects out of the picker: globs on the name, the full path (starting
+# with / or ~) or the path below the project directory, or re: regexps
+# exclude:
+#   - "**/archive/**"
+
+# Project order: frecency, alphabetical, recent or path
+# sort: frecency
+
+# Rank projects by zoxide scores and add the projects you open to zoxide
+# zoxide: true
+
+# Treat unknown commands as project names, so "sesh api" opens the api project
+# quick_connect: true
+
+# Session names: {name}, {dir}, {parent} and {path_hash}, e.g. "{parent}-{name}"
+# session_name: "{name}"
+
+# How to open a session: switch, attach or detach-others
+# attach_mode: switch
+
+# When a session is already attached elsewhere: share, group or mirror
+# multi_client: share
+
+# Editor for the first window of the default layout, defaults to $EDITOR
+# editor: nvim
+
+# Windows for new sessions, instead of the built-in editor/opencode/zsh layout
+# windows:
+#   - name: editor
+#     cmd: nvim .
+#   - name: shell
+
+# Picker colours: preset dark or light, plus title, selected, selected_text,
+# text, path and help as hex colours or ANSI colour numbers
+# theme:
+#   preset: dark
+
+# Write sesh's files readable by you only (0600 and 0700)
+# private_files: true
+
+# debug, info, warn, error or off
+# log_level: info
+`
+
+// WriteInitialConfig writes an annotated config searching dirs to the
+// config file path, replacing any file there, and returns that path
+func WriteInitialConfig(dirs []string) (string, error) {
+	path, err := GetConfigFilePath()
+	if err != nil {
+		return "", fmt.Errorf("failed to get config path: %w", err)
+	}
+	if ext := strings.TrimPrefix(filepath.Ext(path), "."); ext != configType && ext != "yml" {
+		return "", fmt.Errorf("sesh init writes YAML, not %s", path)
+	}
+
+	var list strings.Builder
+	for _, dir := range dirs {
+		value, err := yaml.Marshal(dir)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&list, "  - %s", value)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), xdg.DirMode()); err != nil {
+		return "", fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(fmt.Sprintf(initialConfig, list.String())), xdg.FileMode()); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	return path, nil
+}
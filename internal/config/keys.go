@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// DefaultKeys are the picker actions keys can be bound to under keys:, with
+// the keys they have unless configured. Key names are those bubbletea
+// reports, such as "k", "up", "enter" or "ctrl+x".
+var DefaultKeys = map[string][]string{
+	"up":             {"up", "k"},
+	"down":           {"down", "j"},
+	"select":         {"enter"},
+	"quit":           {"esc"},
+	"mark":           {"tab"},
+	"kill-session":   {"ctrl+x"},
+	"rename-session": {"ctrl+e"},
+	"reload":         {"ctrl+r"},
+	"toggle-sort":    {"ctrl+s"},
+}
+
+// resolveKeys returns the keys for every action: the configured ones, else
+// the defaults minus any keys configured for another action
+func resolveKeys(configured map[string][]string) (map[string][]string, error) {
+	taken := make(map[string]string) // Key to the action it is configured for
+	for _, action := range slices.Sorted(maps.Keys(configured)) {
+		if _, ok := DefaultKeys[action]; !ok {
+			return nil, fmt.Errorf("invalid keys.%s: unknown action, expected one of %s",
+				action, strings.Join(slices.Sorted(maps.Keys(DefaultKeys)), ", "))
+		}
+		if len(configured[action]) == 0 {
+			return nil, fmt.Errorf("invalid keys.%s: no keys given", action)
+		}
+		for _, key := range configured[action] {
+			if key == "ctrl+c" {
+				return nil, fmt.Errorf("invalid keys.%s: ctrl+c always quits", action)
+			}
+			if other, ok := taken[key]; ok && other != action {
+				return nil, fmt.Errorf("invalid keys: %s is bound to both %s and %s", key, other, action)
+			}
+			taken[key] = action
+		}
+	}
+
+	resolved := make(map[string][]string, len(DefaultKeys))
+	for action, keys := range DefaultKeys {
+		if keys, ok := configured[action]; ok {
+			resolved[action] = keys
+			continue
+		}
+		for _, key := range keys {
+			if _, ok := taken[key]; !ok {
+				resolved[action] = append(resolved[action], key)
+			}
+		}
+	}
+	return resolved, nil
+}
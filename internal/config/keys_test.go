@@ -0,0 +1,71 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestResolveKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured map[string][]string
+		want       map[string][]string // Actions to check, the rest keep their defaults
+		wantErr    bool
+	}{
+		{
+			name: "defaults",
+			want: map[string][]string{"up": {"up", "k"}, "rename-session": {"ctrl+e"}},
+		},
+		{
+			name:       "configured replaces the defaults",
+			configured: map[string][]string{"select": {"ctrl+o"}},
+			want:       map[string][]string{"select": {"ctrl+o"}, "quit": {"esc"}},
+		},
+		{
+			name:       "configured key is taken from another action's defaults",
+			configured: map[string][]string{"reload": {"k"}},
+			want:       map[string][]string{"reload": {"k"}, "up": {"up"}},
+		},
+		{
+			name:       "action left without keys",
+			configured: map[string][]string{"toggle-sort": {"enter"}},
+			want:       map[string][]string{"toggle-sort": {"enter"}, "select": nil},
+		},
+		{
+			name:       "unknown action",
+			configured: map[string][]string{"jump": {"g"}},
+			wantErr:    true,
+		},
+		{
+			name:       "no keys",
+			configured: map[string][]string{"up": {}},
+			wantErr:    true,
+		},
+		{
+			name:       "ctrl+c",
+			configured: map[string][]string{"quit": {"ctrl+c"}},
+			wantErr:    true,
+		},
+		{
+			name:       "key bound twice",
+			configured: map[string][]string{"up": {"ctrl+p"}, "reload": {"ctrl+p"}},
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveKeys(tt.configured)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveKeys() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for action, keys := range tt.want {
+				if !slices.Equal(got[action], keys) {
+					t.Errorf("%s = %v, want %v", action, got[action], keys)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Window configures a window in a session layout
+type Window struct {
+	Name     string `mapstructure:"name" json:"name,omitempty"`
+	Command  string `mapstructure:"cmd" json:"cmd,omitempty"`
+	Template string `mapstructure:"template" json:"template,omitempty"` // Name of a window template to start from
+	If       string `mapstructure:"if" json:"if,omitempty"`             // Condition, see EvalCondition
+	Dir      string `mapstructure:"dir" json:"dir,omitempty"`           // Working directory, relative to the project
+
+	// Env holds KEY=VALUE pairs for the window. A list rather than a map
+	// because viper lowercases map keys, which would mangle variable names.
+	Env []string `mapstructure:"env" json:"env,omitempty"`
+
+	// Panes are split off the window after it starts, its own command
+	// running in the first pane. Layout is a tmux layout, such as
+	// main-vertical or tiled, applied once they are all there.
+	Panes  []Pane `mapstructure:"panes" json:"panes,omitempty"`
+	Layout string `mapstructure:"layout" json:"layout,omitempty"`
+}
+
+// Pane configures a pane split off a window. Each pane splits the one before
+// it, the first splitting the window's own pane.
+type Pane struct {
+	Command string `mapstructure:"cmd" json:"cmd,omitempty"`
+	Split   string `mapstructure:"split" json:"split,omitempty"` // vertical (below, the default) or horizontal (to the right)
+	Size    string `mapstructure:"size" json:"size,omitempty"`   // A percentage such as 30%, or a number of lines or columns
+	Dir     string `mapstructure:"dir" json:"dir,omitempty"`     // Working directory, defaults to the window's
+}
+
+// PaneSplits are the directions a pane can be split off in
+var PaneSplits = []string{"vertical", "horizontal"}
+
+// WindowTemplate is a reusable window definition that layouts reference by name
+type WindowTemplate struct {
+	Name    string   `mapstructure:"name" json:"name,omitempty"` // Window name, defaults to the template's key
+	Command string   `mapstructure:"cmd" json:"cmd,omitempty"`
+	If      string   `mapstructure:"if" json:"if,omitempty"`
+	Dir     string   `mapstructure:"dir" json:"dir,omitempty"`
+	Env     []string `mapstructure:"env" json:"env,omitempty"`
+	Panes   []Pane   `mapstructure:"panes" json:"panes,omitempty"`
+	Layout  string   `mapstructure:"layout" json:"layout,omitempty"`
+}
+
+// ResolveWindows expands template references in a window list. Fields set on
+// the window itself take precedence over those from the template.
+func ResolveWindows(windows []Window, templates map[string]WindowTemplate) ([]Window, error) {
+	resolved := make([]Window, 0, len(windows))
+	for i, w := range windows {
+		if w.Template != "" {
+			tmpl, ok := templates[w.Template]
+			if !ok {
+				return nil, fmt.Errorf("window %d references unknown template %q", i+1, w.Template)
+			}
+			if w.Name == "" {
+				w.Name = tmpl.Name
+			}
+			if w.Name == "" {
+				w.Name = w.Template
+			}
+			if w.Command == "" {
+				w.Command = tmpl.Command
+			}
+			if w.If == "" {
+				w.If = tmpl.If
+			}
+			if w.Dir == "" {
+				w.Dir = tmpl.Dir
+			}
+			// Window variables come last so they override the template's
+			w.Env = append(append([]string{}, tmpl.Env...), w.Env...)
+			if len(w.Panes) == 0 {
+				w.Panes = tmpl.Panes
+			}
+			if w.Layout == "" {
+				w.Layout = tmpl.Layout
+			}
+		}
+
+		if w.Name == "" {
+			return nil, fmt.Errorf("window %d has no name", i+1)
+		}
+		if _, err := ParseEnv(w.Env); err != nil {
+			return nil, fmt.Errorf("window %s: %w", w.Name, err)
+		}
+		for j, pane := range w.Panes {
+			if err := pane.validate(); err != nil {
+				return nil, fmt.Errorf("window %s pane %d: %w", w.Name, j+1, err)
+			}
+		}
+		resolved = append(resolved, w)
+	}
+	return resolved, nil
+}
+
+// validate checks a pane's split direction and size
+func (p Pane) validate() error {
+	if p.Split != "" && p.Split != PaneSplits[0] && p.Split != PaneSplits[1] {
+		return fmt.Errorf("invalid split %q: expected %s", p.Split, strings.Join(PaneSplits, " or "))
+	}
+	if p.Size == "" {
+		return nil
+	}
+	if percent, ok := strings.CutSuffix(p.Size, "%"); ok {
+		if n, err := strconv.Atoi(percent); err != nil || n < 1 || n > 99 {
+			return fmt.Errorf("invalid size %q: expected a percentage from 1%% to 99%%", p.Size)
+		}
+		return nil
+	}
+	if n, err := strconv.Atoi(p.Size); err != nil || n < 1 {
+		return fmt.Errorf("invalid size %q: expected a percentage such as 30%% or a number of lines or columns", p.Size)
+	}
+	return nil
+}
+
+// FilterWindows drops windows whose condition doesn't hold for the project
+func FilterWindows(windows []Window, projectPath string) ([]Window, error) {
+	filtered := make([]Window, 0, len(windows))
+	for _, w := range windows {
+		if w.If != "" {
+			ok, err := EvalCondition(w.If, projectPath)
+			if err != nil {
+				return nil, fmt.Errorf("window %s: %w", w.Name, err)
+			}
+			if !ok {
+				continue
+			}
+		}
+		filtered = append(filtered, w)
+	}
+	return filtered, nil
+}
+
+// WindowDir resolves a window's working directory against the project path.
+// Absolute and ~ paths are used as is; an empty dir means the project itself.
+func WindowDir(dir, projectPath string) string {
+	if dir == "" {
+		return projectPath
+	}
+	dir = expandPath(dir)
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(projectPath, dir)
+}
+
+// ParseEnv converts KEY=VALUE pairs into a map; later pairs win
+func ParseEnv(pairs []string) (map[string]string, error) {
+	env := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid env entry %q: expected KEY=VALUE", pair)
+		}
+		env[key] = value
+	}
+	return env, nil
+}
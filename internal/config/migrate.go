@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+	"github.com/spf13/viper"
+	"go.yaml.in/yaml/v3"
+)
+
+// CurrentVersion is the config schema version this sesh reads. Config files
+// record theirs under version:, a missing one meaning 0.
+const CurrentVersion = 1
+
+// migration upgrades a config from one version to the next
+type migration struct {
+	// apply edits the top-level mapping of a YAML config in place
+	apply func(root *yaml.Node) error
+
+	// manual tells users of TOML and JSON configs, which sesh doesn't
+	// rewrite, what to change by hand; empty if nothing needs changing
+	manual string
+}
+
+// migrations[i] upgrades version i to version i+1. Renamed keys and changed
+// structures get a migration here, so old configs keep working rather than
+// failing to load.
+var migrations = []migration{
+	// 0 to 1: the version key was introduced, the schema is unchanged
+	{apply: func(*yaml.Node) error { return nil }},
+}
+
+// configVersion returns the version: of the loaded config
+func configVersion() (int, error) {
+	switch v := viper.Get("version").(type) {
+	case nil:
+		return 0, nil
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case float64:
+		if v == float64(int(v)) {
+			return int(v), nil
+		}
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid version %v: expected a whole number", viper.Get("version"))
+}
+
+// migrateConfig upgrades the config file at path from version to
+// CurrentVersion, returning the upgraded YAML to load in its place, or nil
+// when there is nothing to change. YAML files are rewritten in place,
+// comments included, after copying the original to <path>.v<version>.bak;
+// read-only files, such as one in the nix store, are only upgraded in
+// memory. Other formats are left alone unless an upgrade needs manual
+// changes, which are returned as an error.
+func migrateConfig(path string, version int) ([]byte, error) {
+	switch {
+	case version < 0:
+		return nil, fmt.Errorf("invalid version %d in %s", version, path)
+	case version > CurrentVersion:
+		return nil, fmt.Errorf("%s is config version %d, but this sesh only understands up to version %d: upgrade sesh",
+			path, version, CurrentVersion)
+	case version == CurrentVersion:
+		return nil, nil
+	}
+
+	if ext := filepath.Ext(path); ext != ".yaml" && ext != ".yml" {
+		for v := version; v < CurrentVersion; v++ {
+			if migrations[v].manual != "" {
+				return nil, fmt.Errorf("%s is config version %d and needs upgrading by hand: %s, then set version = %d",
+					path, version, migrations[v].manual, CurrentVersion)
+			}
+		}
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc.Kind == 0 {
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("config file %s is not a YAML mapping", path)
+	}
+
+	original, err := encodeConfig(&doc)
+	if err != nil {
+		return nil, err
+	}
+	for v := version; v < CurrentVersion; v++ {
+		if err := migrations[v].apply(root); err != nil {
+			return nil, fmt.Errorf("failed to upgrade %s to config version %d: %w", path, v+1, err)
+		}
+	}
+	migrated, err := encodeConfig(&doc)
+	if err != nil {
+		return nil, err
+	}
+	// Only adding version: isn't worth a backup and a rewritten file
+	if bytes.Equal(original, migrated) {
+		return nil, nil
+	}
+	setVersion(root, CurrentVersion)
+	if migrated, err = encodeConfig(&doc); err != nil {
+		return nil, err
+	}
+
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	err = os.WriteFile(backup, data, xdg.FileMode())
+	if err == nil {
+		if err = os.WriteFile(path, migrated, xdg.FileMode()); err != nil {
+			_ = os.Remove(backup)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %s is config version %d and can't be upgraded in place (%v), set version: %d after updating it\n",
+			ContractPath(path), version, err, CurrentVersion)
+		return migrated, nil
+	}
+	fmt.Fprintf(os.Stderr, "Upgraded %s to config version %d, the previous file is in %s\n",
+		ContractPath(path), CurrentVersion, ContractPath(backup))
+	return migrated, nil
+}
+
+// encodeConfig writes a YAML config back out with sesh's indentation
+func encodeConfig(doc *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(doc); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode config: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// setVersion sets the version key of a YAML config, adding it at the top if
+// missing
+func setVersion(root *yaml.Node, version int) {
+	value := strconv.Itoa(version)
+	if node := mappingValue(root, "version"); node != nil {
+		node.Kind, node.Tag, node.Value = yaml.ScalarNode, "!!int", value
+		return
+	}
+	root.Content = append([]*yaml.Node{
+		{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"},
+		{Kind: yaml.ScalarNode, Tag: "!!int", Value: value},
+	}, root.Content...)
+}
@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// renameMigration stands in for a real migration, renaming old_key to
+// new_key
+var renameMigration = migration{
+	apply: func(root *yaml.Node) error {
+		if key := mappingKey(root, "old_key"); key != nil {
+			key.Value = "new_key"
+		}
+		return nil
+	},
+	manual: "rename old_key to new_key",
+}
+
+// mappingKey returns the key node named key in a YAML mapping, or nil
+func mappingKey(root *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == key {
+			return root.Content[i]
+		}
+	}
+	return nil
+}
+
+// withMigrations replaces the migrations for the rest of the test
+func withMigrations(t *testing.T, m []migration) {
+	saved := migrations
+	migrations = m
+	t.Cleanup(func() { migrations = saved })
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMigrateConfigVersions(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "editor: vim\n")
+	tests := []struct {
+		version int
+		wantErr bool
+	}{
+		{version: -1, wantErr: true},
+		{version: CurrentVersion + 1, wantErr: true},
+		{version: CurrentVersion},
+		// The only migration so far just adds version:, which isn't written
+		{version: 0},
+	}
+	for _, tt := range tests {
+		migrated, err := migrateConfig(path, tt.version)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("migrateConfig(version %d) error = %v, want error %v", tt.version, err, tt.wantErr)
+		}
+		if migrated != nil {
+			t.Errorf("migrateConfig(version %d) = %q, want nil", tt.version, migrated)
+		}
+	}
+	if _, err := os.Stat(path + ".v0.bak"); !os.IsNotExist(err) {
+		t.Errorf("backup written for a config with nothing to upgrade")
+	}
+}
+
+func TestMigrateConfigRewritesYAML(t *testing.T) {
+	withMigrations(t, []migration{renameMigration})
+	original := "# my config\nold_key: 1 # keep me\neditor: vim\n"
+	path := writeConfigFile(t, "config.yaml", original)
+
+	migrated, err := migrateConfig(path, 0)
+	if err != nil {
+		t.Fatalf("migrateConfig: %v", err)
+	}
+	want := "version: 1\n# my config\nnew_key: 1 # keep me\neditor: vim\n"
+	if string(migrated) != want {
+		t.Errorf("migrated config = %q, want %q", migrated, want)
+	}
+	if data, _ := os.ReadFile(path); string(data) != want {
+		t.Errorf("config file = %q, want %q", data, want)
+	}
+	if data, _ := os.ReadFile(path + ".v0.bak"); string(data) != original {
+		t.Errorf("backup = %q, want %q", data, original)
+	}
+}
+
+func TestMigrateConfigOtherFormats(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", "old_key = 1\n")
+
+	withMigrations(t, []migration{{apply: renameMigration.apply}})
+	if migrated, err := migrateConfig(path, 0); err != nil || migrated != nil {
+		t.Errorf("migrateConfig() = %q, %v, want nil, nil", migrated, err)
+	}
+
+	withMigrations(t, []migration{renameMigration})
+	_, err := migrateConfig(path, 0)
+	if err == nil || !strings.Contains(err.Error(), renameMigration.manual) {
+		t.Errorf("migrateConfig() error = %v, want the manual step", err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "old_key = 1\n" {
+		t.Errorf("TOML config was rewritten: %q", data)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"regexp"
+)
+
+// defaultSessionName names sessions after their project
+const defaultSessionName = "{name}"
+
+// sessionNamePlaceholder matches the {field} placeholders in session_name
+var sessionNamePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// sessionNameFields returns the values session_name placeholders stand for:
+// the project name, the names of its directory and the one above, and a
+// short hash of its path
+func sessionNameFields(name, path string) map[string]string {
+	sum := sha256.Sum256([]byte(path))
+	return map[string]string{
+		"name":      name,
+		"dir":       filepath.Base(path),
+		"parent":    filepath.Base(filepath.Dir(path)),
+		"path_hash": hex.EncodeToString(sum[:])[:6],
+	}
+}
+
+// ExpandSessionName fills in a session_name template for the project with
+// the given name and path. The result still needs making safe for tmux.
+func ExpandSessionName(template, name, path string) string {
+	if template == "" {
+		template = defaultSessionName
+	}
+	fields := sessionNameFields(name, path)
+	return sessionNamePlaceholder.ReplaceAllStringFunc(template, func(m string) string {
+		return fields[m[1:len(m)-1]]
+	})
+}
+
+// validateSessionName reports placeholders session_name doesn't know
+func validateSessionName(template string) error {
+	fields := sessionNameFields("", "")
+	for _, m := range sessionNamePlaceholder.FindAllStringSubmatch(template, -1) {
+		if _, ok := fields[m[1]]; !ok {
+			return fmt.Errorf("invalid session_name %q: unknown placeholder {%s}, expected {name}, {dir}, {parent} or {path_hash}", template, m[1])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// profileOverride is the profile chosen with SetProfile
+var profileOverride string
+
+// SetProfile makes LoadConfig apply the named entry of profiles: on top of
+// the rest of the config
+func SetProfile(name string) {
+	profileOverride = name
+}
+
+// ActiveProfile returns the profile chosen with SetProfile or, failing that,
+// the SESH_PROFILE environment variable; empty means no profile
+func ActiveProfile() string {
+	if profileOverride != "" {
+		return profileOverride
+	}
+	return os.Getenv("SESH_PROFILE")
+}
+
+// applyProfile checks every profile in the config and merges the active one
+// over the settings read so far. Maps such as templates are merged key by
+// key; any other setting the profile gives replaces the main one.
+func applyProfile(file string) error {
+	profiles := viper.GetStringMap("profiles")
+	for name, settings := range profiles {
+		if err := checkProfile(file, name, settings); err != nil {
+			return err
+		}
+	}
+
+	name := ActiveProfile()
+	if name == "" {
+		return nil
+	}
+	// Viper lowercases keys, so profile names are case-insensitive
+	settings, ok := profiles[strings.ToLower(name)].(map[string]any)
+	if !ok {
+		if len(profiles) == 0 {
+			return fmt.Errorf("unknown profile %q: the config defines no profiles", name)
+		}
+		return fmt.Errorf("unknown profile %q (available: %s)", name,
+			strings.Join(slices.Sorted(maps.Keys(profiles)), ", "))
+	}
+
+	if err := viper.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to apply profile %s: %w", name, err)
+	}
+	// Lists combined by applyIncludes were set explicitly, which outranks
+	// merged config, so a profile's own lists have to be set the same way
+	for _, key := range appendedKeys {
+		if value, ok := settings[key]; ok {
+			viper.Set(key, value)
+		}
+	}
+	return nil
+}
+
+// checkProfile decodes a profile strictly so mistakes in profiles that
+// aren't active are caught too
+func checkProfile(file, name string, settings any) error {
+	values, ok := settings.(map[string]any)
+	if !ok {
+		return fmt.Errorf("%sprofile %s must be a mapping of settings", keyLine(file, "profiles."+name), name)
+	}
+	for _, key := range []string{"include", "profiles"} {
+		if _, ok := values[key]; ok {
+			return fmt.Errorf("%sprofile %s can't set %s", keyLine(file, "profiles."+name+"."+key), name, key)
+		}
+	}
+
+	v := viper.New()
+	if err := v.MergeConfigMap(values); err != nil {
+		return fmt.Errorf("failed to read profile %s: %w", name, err)
+	}
+	var c Config
+	if err := v.UnmarshalExact(&c, viper.DecodeHook(decodeHook)); err != nil {
+		return schemaErrorAt(file, "profiles."+name, err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/viper"
+)
+
+// projectConfigFiles are the project-local config files, in order of preference
+var projectConfigFiles = []string{".sesh.yaml", filepath.Join(".sesh", "config.yaml")}
+
+// ProjectConfig is a project-local override of the global session layout
+type ProjectConfig struct {
+	Path     string   `mapstructure:"-" json:"-"` // File the config was read from
+	Windows  []Window `mapstructure:"windows" json:"windows,omitempty"`
+	Template string   `mapstructure:"template" json:"template,omitempty"` // Session template to use instead of the configured rules
+
+	// Env holds KEY=VALUE pairs set in the session environment
+	Env []string `mapstructure:"env" json:"env,omitempty"`
+
+	// Startup holds commands run one after another, in a window of their own,
+	// when the project's session is created, e.g. docker compose up -d
+	Startup []string `mapstructure:"startup" json:"startup,omitempty"`
+
+	// CheckoutDefaultOnCreate overrides the global setting for this project
+	CheckoutDefaultOnCreate *bool `mapstructure:"checkout_default_on_create" json:"checkout_default_on_create,omitempty"`
+}
+
+// LoadProjectConfig reads the .sesh.yaml (or .sesh/config.yaml) in a project
+// root. It returns nil when the project has neither.
+func LoadProjectConfig(projectPath string) (*ProjectConfig, error) {
+	for _, name := range projectConfigFiles {
+		path := filepath.Join(projectPath, name)
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		// A separate viper instance so the global config is left untouched
+		v := viper.New()
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+
+		pc := ProjectConfig{Path: path}
+		if err := v.UnmarshalExact(&pc); err != nil {
+			return nil, schemaError(path, err)
+		}
+		if _, err := ParseEnv(pc.Env); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		return &pc, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,39 @@
+package config
+
+import "fmt"
+
+// Ranking tunes frecency ordering: how many projects sesh remembers as
+// recently opened, how far they are lifted above the rest, and how much
+// zoxide scores count
+type Ranking struct {
+	RecentSize   int     `mapstructure:"recent_size" json:"recent_size"`     // Projects kept in the recent list
+	RecentBoost  float64 `mapstructure:"recent_boost" json:"recent_boost"`   // Added to the most recently opened project's score
+	RecentStep   float64 `mapstructure:"recent_step" json:"recent_step"`     // Boost lost per place further down the recent list
+	ZoxideWeight float64 `mapstructure:"zoxide_weight" json:"zoxide_weight"` // Multiplies zoxide scores
+}
+
+// DefaultRanking puts the three projects opened last first, then orders by
+// zoxide score, which rarely reaches the boosts
+var DefaultRanking = Ranking{RecentSize: 3, RecentBoost: 10000, RecentStep: 1000, ZoxideWeight: 1}
+
+// Boost returns the score added to the project at the given place in the
+// recent list, counting from 1. It never goes below 0.
+func (r Ranking) Boost(rank int) float64 {
+	return max(0, r.RecentBoost-float64(rank-1)*r.RecentStep)
+}
+
+// validate rejects negative settings
+func (r Ranking) validate() error {
+	for _, v := range []struct {
+		key   string
+		value float64
+	}{
+		{"recent_size", float64(r.RecentSize)}, {"recent_boost", r.RecentBoost},
+		{"recent_step", r.RecentStep}, {"zoxide_weight", r.ZoxideWeight},
+	} {
+		if v.value < 0 {
+			return fmt.Errorf("invalid ranking.%s %v: must not be negative", v.key, v.value)
+		}
+	}
+	return nil
+}
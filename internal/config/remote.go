@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// remoteScheme prefixes project directories on other machines, reached over
+// SSH: ssh://host:path
+const remoteScheme = "ssh://"
+
+// ParseRemote splits an ssh://host:path location into the SSH host and the
+// path on it, reporting whether location is remote at all. A missing path
+// means the remote home directory.
+func ParseRemote(location string) (host, path string, ok bool) {
+	rest, ok := strings.CutPrefix(location, remoteScheme)
+	if !ok {
+		return "", "", false
+	}
+	host, path, _ = strings.Cut(rest, ":")
+	if path == "" {
+		path = "~"
+	}
+	return host, path, true
+}
+
+// RemoteLocation joins an SSH host and a path on it into an ssh://host:path
+// location, the form remote projects are recorded under
+func RemoteLocation(host, path string) string {
+	return remoteScheme + host + ":" + path
+}
+
+// validateRemote checks the host of a remote project directory
+func validateRemote(location string) error {
+	host, _, _ := ParseRemote(location)
+	if host == "" || strings.HasPrefix(host, "-") || strings.ContainsAny(host, " \t/") {
+		return fmt.Errorf("invalid remote project directory %q, expected ssh://host:path", location)
+	}
+	return nil
+}
+
+// Remote returns the SSH host and remote path of a project directory given
+// as ssh://host:path, reporting whether it is one
+func (d ProjectDirectory) Remote() (host, path string, ok bool) {
+	return ParseRemote(d.Path)
+}
+
+// SkipPatterns returns the skip_dirs patterns applying to the directory
+func (d ProjectDirectory) SkipPatterns() []string {
+	return d.skipDirs
+}
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// decodeErrorLine matches one problem in a mapstructure error, e.g.
+// 'windows[0]' has invalid keys: comand
+var decodeErrorLine = regexp.MustCompile(`^'([^']*)' (.*)$`)
+
+// schemaError rewrites a strict decoding error from file into one line per
+// problem, pointing at the offending key's line where it can be found
+func schemaError(file string, err error) error {
+	return schemaErrorAt(file, "", err)
+}
+
+// schemaErrorAt is schemaError for settings decoded from below prefix in
+// file, such as profiles.work
+func schemaErrorAt(file, prefix string, err error) error {
+	doc := loadYAMLNode(file)
+
+	var problems []string
+	for _, line := range strings.Split(err.Error(), "\n") {
+		m := decodeErrorLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+		path, message := joinKeyPath(prefix, m[1]), m[2]
+
+		if keys, ok := strings.CutPrefix(message, "has invalid keys: "); ok {
+			for _, key := range strings.Split(keys, ", ") {
+				keyPath := joinKeyPath(path, key)
+				problems = append(problems, locate(file, doc, keyPath)+"unknown key "+keyPath)
+			}
+			continue
+		}
+
+		// "cannot parse value as 'int': strconv.ParseInt: invalid syntax"
+		if _, want, ok := strings.Cut(message, "as '"); ok {
+			want, _, _ = strings.Cut(want, "'")
+			message = "expected " + want
+		}
+		problems = append(problems, locate(file, doc, path)+path+": "+message)
+	}
+
+	if len(problems) == 0 {
+		return fmt.Errorf("invalid config %s: %w", file, err)
+	}
+	return fmt.Errorf("invalid config:\n  %s", strings.Join(problems, "\n  "))
+}
+
+// locate returns a "file:line: " prefix for a key path such as
+// templates[go].windows[1].cmd, or just "file: " if it can't be found
+func locate(file string, doc *yaml.Node, keyPath string) string {
+	if line := findLine(doc, keyPath); line > 0 {
+		return fmt.Sprintf("%s:%d: ", file, line)
+	}
+	return file + ": "
+}
+
+// loadYAMLNode parses a YAML config file into a node tree for line lookups,
+// returning nil for other formats or unreadable files
+func loadYAMLNode(file string) *yaml.Node {
+	if ext := filepath.Ext(file); ext != ".yaml" && ext != ".yml" {
+		return nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// findLine follows a mapstructure key path through the node tree and returns
+// the line it ends on, or 0. Keys are compared case-insensitively because
+// viper lowercases them.
+func findLine(node *yaml.Node, keyPath string) int {
+	if node == nil {
+		return 0
+	}
+
+	line := node.Line
+	for _, seg := range splitKeyPath(keyPath) {
+		switch node.Kind {
+		case yaml.MappingNode:
+			var next *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if strings.EqualFold(node.Content[i].Value, seg) {
+					line, next = node.Content[i].Line, node.Content[i+1]
+					break
+				}
+			}
+			if next == nil {
+				return 0
+			}
+			node = next
+		case yaml.SequenceNode:
+			i, err := strconv.Atoi(seg)
+			if err != nil || i < 0 || i >= len(node.Content) {
+				return 0
+			}
+			node = node.Content[i]
+			line = node.Line
+		default:
+			return 0
+		}
+	}
+	return line
+}
+
+// splitKeyPath splits "a[0].b[key]" into a, 0, b, key
+func splitKeyPath(keyPath string) []string {
+	var segs []string
+	for _, part := range strings.Split(strings.ReplaceAll(keyPath, "[", ".["), ".") {
+		part = strings.TrimSuffix(strings.TrimPrefix(part, "["), "]")
+		if part != "" {
+			segs = append(segs, part)
+		}
+	}
+	return segs
+}
+
+// joinKeyPath appends key to a dotted key path, which may be empty
+func joinKeyPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	if key == "" {
+		return path
+	}
+	return path + "." + key
+}
+
+// keyLine returns a "file:line: " prefix for a key path in a YAML config
+// file
+func keyLine(file, key string) string {
+	return locate(file, loadYAMLNode(file), key)
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const schemaTestConfig = `editor: vim
+templates:
+  go:
+    windows:
+      - name: editor
+        cmd: nvim
+      - name: tests
+        comand: go test ./...
+profiles:
+  work:
+    Search_Paths:
+      - ~/work
+`
+
+func TestFindLine(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", schemaTestConfig)
+	doc := loadYAMLNode(path)
+
+	tests := []struct {
+		keyPath string
+		want    int
+	}{
+		{"editor", 1},
+		{"templates", 2},
+		{"templates.go.windows", 4},
+		{"templates[go].windows[0]", 5},
+		{"templates[go].windows[1].comand", 8},
+		{"profiles.work.search_paths", 11},
+		{"profiles.work.search_paths[0]", 12},
+		{"templates[go].windows[2]", 0},
+		{"templates[go].windows[x]", 0},
+		{"editor.name", 0},
+		{"missing", 0},
+	}
+	for _, tt := range tests {
+		if got := findLine(doc, tt.keyPath); got != tt.want {
+			t.Errorf("findLine(%q) = %d, want %d", tt.keyPath, got, tt.want)
+		}
+	}
+
+	if got := findLine(nil, "editor"); got != 0 {
+		t.Errorf("findLine(nil) = %d, want 0", got)
+	}
+}
+
+func TestSplitKeyPath(t *testing.T) {
+	tests := []struct {
+		keyPath string
+		want    []string
+	}{
+		{"editor", []string{"editor"}},
+		{"a[0].b[key]", []string{"a", "0", "b", "key"}},
+		{"templates[go].windows[1].cmd", []string{"templates", "go", "windows", "1", "cmd"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := splitKeyPath(tt.keyPath); !slices.Equal(got, tt.want) {
+			t.Errorf("splitKeyPath(%q) = %q, want %q", tt.keyPath, got, tt.want)
+		}
+	}
+}
+
+func TestSchemaError(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", schemaTestConfig)
+	decodeErr := errors.Join(
+		errors.New("'templates[go].windows[1]' has invalid keys: comand"),
+		errors.New("'editor' expected type 'string', got unconvertible type 'int'"),
+	)
+
+	got := schemaError(path, decodeErr).Error()
+	for _, want := range []string{
+		path + ":8: unknown key templates[go].windows[1].comand",
+		path + ":1: editor: expected type 'string', got unconvertible type 'int'",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("schemaError() = %q, want it to contain %q", got, want)
+		}
+	}
+
+	got = schemaErrorAt(path, "profiles.work", errors.New("'search_paths[0]' cannot parse value as 'int': invalid syntax")).Error()
+	if want := path + ":12: profiles.work.search_paths[0]: expected int"; !strings.Contains(got, want) {
+		t.Errorf("schemaErrorAt() = %q, want it to contain %q", got, want)
+	}
+}
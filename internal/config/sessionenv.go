@@ -0,0 +1,47 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// EnvFilter controls which variables of the launching environment new
+// sessions see. Both lists hold globs such as AWS_*; an empty Allow lets
+// everything through that Deny doesn't block.
+type EnvFilter struct {
+	Allow []string `mapstructure:"allow" json:"allow,omitempty"`
+	Deny  []string `mapstructure:"deny" json:"deny,omitempty"`
+}
+
+// Active reports whether the filter removes anything at all
+func (f EnvFilter) Active() bool {
+	return len(f.Allow) > 0 || len(f.Deny) > 0
+}
+
+// Allows reports whether a variable may be passed into a new session
+func (f EnvFilter) Allows(name string) bool {
+	if matchAny(f.Deny, name) {
+		return false
+	}
+	return len(f.Allow) == 0 || matchAny(f.Allow, name)
+}
+
+// validate checks that the patterns are well formed
+func (f EnvFilter) validate() error {
+	for _, pattern := range append(append([]string{}, f.Allow...), f.Deny...) {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid session_env pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchAny reports whether name matches one of the globs
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SharedTemplatesDir returns the directory sesh templates sync clones
+// template repositories into
+func SharedTemplatesDir() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configPath, "templates"), nil
+}
+
+// loadSharedTemplates adds the templates of every synced repository, one
+// per YAML file at the top of the repository and named after it. Templates
+// in the config win, and between repositories the first by name wins.
+func (c *Config) loadSharedTemplates() error {
+	dir, err := SharedTemplatesDir()
+	if err != nil {
+		return err
+	}
+	repos, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read shared templates: %w", err)
+	}
+
+	for _, repo := range repos {
+		if !repo.IsDir() || strings.HasPrefix(repo.Name(), ".") {
+			continue
+		}
+		files, err := templateFiles(filepath.Join(dir, repo.Name()))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			name := strings.ToLower(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)))
+			if _, ok := c.Templates[name]; ok {
+				continue
+			}
+
+			v := viper.New()
+			v.SetConfigFile(file)
+			if err := v.ReadInConfig(); err != nil {
+				return fmt.Errorf("failed to read template %s: %w", file, err)
+			}
+			var tmpl SessionTemplate
+			if err := v.UnmarshalExact(&tmpl, viper.DecodeHook(decodeHook)); err != nil {
+				return schemaError(file, err)
+			}
+			tmpl.Source = repo.Name()
+
+			if c.Templates == nil {
+				c.Templates = make(map[string]SessionTemplate)
+			}
+			c.Templates[name] = tmpl
+		}
+	}
+	return nil
+}
+
+// templateFiles lists the YAML files at the top of a template repository
+func templateFiles(repo string) ([]string, error) {
+	entries, err := os.ReadDir(repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shared templates: %w", err)
+	}
+
+	var files []string
+	for _, e := range entries {
+		ext := filepath.Ext(e.Name())
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		files = append(files, filepath.Join(repo, e.Name()))
+	}
+	sort.Strings(files)
+	return files, nil
+}
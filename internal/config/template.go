@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SessionTemplate is a named session layout that projects can be mapped to
+type SessionTemplate struct {
+	Windows []Window `mapstructure:"windows" json:"windows,omitempty"`
+	Env     []string `mapstructure:"env" json:"env,omitempty"` // KEY=VALUE pairs for the session
+
+	// Options are tmux session options, such as status-style or mouse, set
+	// on sessions created from the template
+	Options map[string]string `mapstructure:"options" json:"options,omitempty"`
+
+	// Source is the synced repository the template came from, empty for
+	// templates defined in the config
+	Source string `mapstructure:"-" json:"source,omitempty"`
+}
+
+// TemplateRule maps projects to a session template. Match is a glob against
+// the project path if it contains a slash or starts with ~, otherwise
+// against the project name.
+type TemplateRule struct {
+	Match    string `mapstructure:"match" json:"match"`
+	Template string `mapstructure:"template" json:"template"`
+}
+
+// TemplateFor returns the name of the session template for a project: the
+// first matching rule, else the default template, else "" to use the
+// windows config. explicit is a template the project asked for itself,
+// e.g. in its .sesh.yaml, and wins over both.
+func (c *Config) TemplateFor(explicit, projectName, projectPath string) (string, error) {
+	if explicit != "" {
+		if _, ok := c.Template(explicit); !ok {
+			return "", fmt.Errorf("unknown template %q", explicit)
+		}
+		return explicit, nil
+	}
+
+	for _, rule := range c.TemplateRules {
+		ok, err := rule.matches(projectName, projectPath)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			return rule.Template, nil
+		}
+	}
+	return c.DefaultTemplate, nil
+}
+
+// Template looks up a session template by name. Names are matched
+// case-insensitively since viper lowercases map keys.
+func (c *Config) Template(name string) (SessionTemplate, bool) {
+	tmpl, ok := c.Templates[strings.ToLower(name)]
+	return tmpl, ok
+}
+
+// matches reports whether a rule applies to the project
+func (r TemplateRule) matches(projectName, projectPath string) (bool, error) {
+	pattern, subject := r.Match, projectName
+	if strings.HasPrefix(pattern, "~") || strings.Contains(pattern, "/") {
+		pattern, subject = expandPath(pattern), projectPath
+	}
+
+	ok, err := filepath.Match(pattern, subject)
+	if err != nil {
+		return false, fmt.Errorf("invalid template rule %q: %w", r.Match, err)
+	}
+	return ok, nil
+}
+
+// validateTemplates checks that templates are well formed and that rules
+// and the default only reference templates that exist
+func (c *Config) validateTemplates() error {
+	for name, tmpl := range c.Templates {
+		if len(tmpl.Windows) == 0 {
+			return fmt.Errorf("template %s has no windows", name)
+		}
+		if _, err := ParseEnv(tmpl.Env); err != nil {
+			return fmt.Errorf("template %s: %w", name, err)
+		}
+		for option := range tmpl.Options {
+			if option == "" || strings.HasPrefix(option, "-") || strings.ContainsAny(option, " \t") {
+				return fmt.Errorf("template %s: invalid tmux option %q", name, option)
+			}
+		}
+	}
+
+	for _, rule := range c.TemplateRules {
+		if rule.Match == "" {
+			return fmt.Errorf("template rule for %q has no match", rule.Template)
+		}
+		if _, err := filepath.Match(rule.Match, ""); err != nil {
+			return fmt.Errorf("invalid template rule %q: %w", rule.Match, err)
+		}
+		if _, ok := c.Template(rule.Template); !ok {
+			return fmt.Errorf("template rule %q references unknown template %q", rule.Match, rule.Template)
+		}
+	}
+
+	if c.DefaultTemplate != "" {
+		if _, ok := c.Template(c.DefaultTemplate); !ok {
+			return fmt.Errorf("default_template references unknown template %q", c.DefaultTemplate)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ThemePresets are the built-in picker colour schemes, the default first
+var ThemePresets = []string{"dark", "light"}
+
+// Theme sets the picker's colours: a preset, with any colours given here on
+// top. Colours are hex values such as #7D56F4 or ANSI colour numbers 0-255.
+type Theme struct {
+	Preset       string `mapstructure:"preset" json:"preset"`
+	Title        string `mapstructure:"title" json:"title,omitempty"`
+	Selected     string `mapstructure:"selected" json:"selected,omitempty"` // Background of the highlighted item
+	SelectedText string `mapstructure:"selected_text" json:"selected_text,omitempty"`
+	Text         string `mapstructure:"text" json:"text,omitempty"`
+	Path         string `mapstructure:"path" json:"path,omitempty"`
+	Help         string `mapstructure:"help" json:"help,omitempty"`
+}
+
+// hexColor matches #RGB and #RRGGBB colours
+var hexColor = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// validate checks the preset and that every colour set is one lipgloss understands
+func (t Theme) validate() error {
+	if !slices.Contains(ThemePresets, t.Preset) {
+		return fmt.Errorf("invalid theme.preset %q: expected %s", t.Preset, strings.Join(ThemePresets, " or "))
+	}
+
+	colors := []struct{ key, value string }{
+		{"title", t.Title}, {"selected", t.Selected}, {"selected_text", t.SelectedText},
+		{"text", t.Text}, {"path", t.Path}, {"help", t.Help},
+	}
+	for _, c := range colors {
+		if c.value == "" || hexColor.MatchString(c.value) {
+			continue
+		}
+		if n, err := strconv.Atoi(c.value); err == nil && n >= 0 && n <= 255 {
+			continue
+		}
+		return fmt.Errorf("invalid theme.%s %q: expected a hex colour like #7D56F4 or an ANSI colour number 0-255", c.key, c.value)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/finder"
+)
+
+// errNotFound is returned by get when the daemon has nothing at the path
+var errNotFound = errors.New("not found")
+
+// client returns an HTTP client that talks to the daemon's unix socket
+func client(timeout time.Duration) (*http.Client, error) {
+	socketPath, err := SocketPath()
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", socketPath)
+			},
+		},
+	}, nil
+}
+
+// get performs a GET request against the daemon
+func get(path string, timeout time.Duration) ([]byte, error) {
+	c, err := client(timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The host is ignored; requests always go to the socket
+	resp, err := c.Get("http://sesh" + path)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("daemon error: %s", strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// IsRunning reports whether a daemon is answering on the socket
+func IsRunning() bool {
+	_, err := get("/metrics", 200*time.Millisecond)
+	return err == nil
+}
+
+// FetchProjects asks a running daemon for the project list
+func FetchProjects() ([]finder.Project, error) {
+	body, err := get("/projects", 30*time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []finder.Project
+	if err := json.Unmarshal(body, &projects); err != nil {
+		return nil, fmt.Errorf("invalid daemon response: %w", err)
+	}
+	return projects, nil
+}
+
+// FindProject asks a running daemon for the project called name. During a
+// scan the daemon answers as soon as it finds one. A nil project means there
+// is none.
+func FindProject(name string) (*finder.Project, error) {
+	body, err := get("/projects?name="+url.QueryEscape(name), 30*time.Second)
+	if errors.Is(err, errNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var project finder.Project
+	if err := json.Unmarshal(body, &project); err != nil {
+		return nil, fmt.Errorf("invalid daemon response: %w", err)
+	}
+	return &project, nil
+}
+
+// Reload asks a running daemon to reload its config and rescan
+func Reload() error {
+	c, err := client(10 * time.Second)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Post("http://sesh/reload", "", nil)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("daemon error: %s", strings.TrimSpace(string(body)))
+	}
+	return nil
+}
+
+// FetchMetrics returns the daemon's metrics as a name to value map
+func FetchMetrics() (map[string]float64, error) {
+	body, err := get("/metrics", time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(string(body)))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		if v, err := strconv.ParseFloat(fields[1], 64); err == nil {
+			values[fields[0]] = v
+		}
+	}
+	return values, nil
+}
@@ -0,0 +1,97 @@
+package daemon
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// metrics tracks daemon activity for the /metrics endpoint
+type metrics struct {
+	mu           sync.Mutex
+	started      time.Time
+	requests     map[string]int
+	scans        int
+	lastScan     time.Duration
+	totalScan    time.Duration
+	cacheHits    int
+	cacheMisses  int
+	coalescedReq int
+	reloads      int
+	lastScanTime time.Time
+}
+
+func newMetrics() *metrics {
+	return &metrics{
+		started:  time.Now(),
+		requests: make(map[string]int),
+	}
+}
+
+func (m *metrics) request(path string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.requests[path]++
+}
+
+func (m *metrics) cacheHit() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheHits++
+}
+
+func (m *metrics) cacheMiss() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cacheMisses++
+}
+
+func (m *metrics) coalesced() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.coalescedReq++
+}
+
+func (m *metrics) reloaded() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.reloads++
+}
+
+func (m *metrics) scanned(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scans++
+	m.lastScan = d
+	m.totalScan += d
+	m.lastScanTime = time.Now()
+}
+
+// write renders the metrics in the Prometheus text exposition format
+func (m *metrics) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintf(w, "sesh_uptime_seconds %.0f\n", time.Since(m.started).Seconds())
+	fmt.Fprintf(w, "sesh_scans_total %d\n", m.scans)
+	fmt.Fprintf(w, "sesh_scan_duration_seconds_last %.6f\n", m.lastScan.Seconds())
+	fmt.Fprintf(w, "sesh_scan_duration_seconds_sum %.6f\n", m.totalScan.Seconds())
+	if !m.lastScanTime.IsZero() {
+		fmt.Fprintf(w, "sesh_scan_last_timestamp_seconds %d\n", m.lastScanTime.Unix())
+	}
+	fmt.Fprintf(w, "sesh_cache_hits_total %d\n", m.cacheHits)
+	fmt.Fprintf(w, "sesh_cache_misses_total %d\n", m.cacheMisses)
+	fmt.Fprintf(w, "sesh_scans_coalesced_total %d\n", m.coalescedReq)
+	fmt.Fprintf(w, "sesh_config_reloads_total %d\n", m.reloads)
+
+	paths := make([]string, 0, len(m.requests))
+	for p := range m.requests {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		fmt.Fprintf(w, "sesh_requests_total{path=%q} %d\n", p, m.requests[p])
+	}
+}
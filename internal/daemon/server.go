@@ -0,0 +1,375 @@
+package daemon
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/finder"
+)
+
+// scanTTL is how long scan results are served before the next request rescans
+const scanTTL = 30 * time.Second
+
+// requestTimeout is how long a request waits on a scan before giving up,
+// short of the clients' own timeout so they get the reason
+const requestTimeout = 25 * time.Second
+
+// Reloader reads the config again, returning the one the daemon should
+// serve from then on
+type Reloader func() (*config.Config, error)
+
+// Server keeps project scan results in memory and serves them over a unix socket
+type Server struct {
+	reload    Reloader
+	reloading sync.Mutex      // Held while a reload runs, so reloads take turns
+	scanCtx   context.Context // Cancelled when the server stops, ending scans
+	stopScans context.CancelFunc
+
+	mu       sync.Mutex
+	cfg      *config.Config // Each scan keeps the config it started with
+	projects []finder.Project
+	scanned  time.Time
+	scanning *scan // The scan in progress, if any
+
+	metrics *metrics
+}
+
+// scan is a filesystem scan in progress. Requests that arrive while it runs
+// wait for it and share its results rather than starting scans of their own.
+type scan struct {
+	done     chan struct{}
+	projects []finder.Project
+	err      error
+
+	mu      sync.Mutex
+	found   []finder.Project // Projects found so far, in the order found
+	updated chan struct{}    // Closed and replaced whenever found grows
+}
+
+// add publishes a project found by the scan to anyone waiting for it
+func (sc *scan) add(p finder.Project) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.found = append(sc.found, p)
+	close(sc.updated)
+	sc.updated = make(chan struct{})
+}
+
+// progress returns the projects found so far and a channel closed when
+// there are more
+func (sc *scan) progress() ([]finder.Project, <-chan struct{}) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	return sc.found, sc.updated
+}
+
+// NewServer creates a server that scans the project directories of cfg and
+// serves its listed projects along with those found. reload, which may be
+// nil, is called on SIGHUP and reload requests to pick up config changes.
+func NewServer(cfg *config.Config, reload Reloader) *Server {
+	scanCtx, stopScans := context.WithCancel(context.Background())
+	return &Server{
+		reload:    reload,
+		scanCtx:   scanCtx,
+		stopScans: stopScans,
+		cfg:       cfg,
+		metrics:   newMetrics(),
+	}
+}
+
+// SocketPath returns the path of the daemon's unix socket. Each profile has
+// its own, since the daemon scans the profile's directories, and so does a
+// config file chosen with --config or SESH_CONFIG, so clients reading
+// another config scan for themselves rather than get the daemon's projects.
+func SocketPath() (string, error) {
+	cacheDir, err := cache.ProfileDir()
+	if err != nil {
+		return "", err
+	}
+
+	file := config.ExplicitConfigFile()
+	if file == "" {
+		return filepath.Join(cacheDir, "sesh.sock"), nil
+	}
+	if abs, err := filepath.Abs(file); err == nil {
+		file = abs
+	}
+	if resolved, err := filepath.EvalSymlinks(file); err == nil {
+		file = resolved
+	}
+	sum := sha256.Sum256([]byte(file))
+	return filepath.Join(cacheDir, fmt.Sprintf("sesh-%x.sock", sum[:6])), nil
+}
+
+// Run serves requests until the process receives SIGINT or SIGTERM,
+// reloading the config on SIGHUP
+func (s *Server) Run() error {
+	socketPath, err := SocketPath()
+	if err != nil {
+		return err
+	}
+
+	if IsRunning() {
+		return fmt.Errorf("sesh serve is already running (%s)", socketPath)
+	}
+	// A socket left behind by a daemon that crashed
+	_ = os.Remove(socketPath)
+
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", socketPath, err)
+	}
+	defer os.Remove(socketPath)
+	defer s.stopScans()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/projects", s.handleProjects)
+	mux.HandleFunc("/metrics", s.handleMetrics)
+	mux.HandleFunc("/reload", s.handleReload)
+	server := &http.Server{Handler: mux}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		s.stopScans()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	go func() {
+		for range hup {
+			if err := s.Reload(); err != nil {
+				slog.Error("config reload failed, keeping the previous config", "error", err)
+			}
+		}
+	}()
+
+	slog.Info("daemon started", "socket", socketPath)
+	fmt.Printf("sesh serve listening on %s\n", socketPath)
+
+	if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	slog.Info("daemon stopped")
+	return nil
+}
+
+// Reload reads the config again and rescans with it. The cached projects are
+// dropped, so requests wait for the new scan rather than getting results
+// from the old directories. If the config fails to load the server keeps the
+// one it has. Reloads from SIGHUP and requests arriving together run one
+// after the other.
+func (s *Server) Reload() error {
+	if s.reload == nil {
+		return fmt.Errorf("this daemon can't reload its config")
+	}
+	s.reloading.Lock()
+	defer s.reloading.Unlock()
+	cfg, err := s.reload()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.cfg = cfg
+	s.projects = nil
+	// A scan of the old directories still finishes for those waiting on
+	// it, but its results aren't kept
+	s.scanning = nil
+	s.mu.Unlock()
+
+	s.metrics.reloaded()
+	slog.Info("config reloaded", "directories", len(cfg.ProjectDirectories))
+	s.cachedOrScan()
+	return nil
+}
+
+// Projects returns the scanned projects, rescanning if the results are
+// stale. Only one scan runs at a time; concurrent callers share it. If ctx
+// is done first Projects returns its error, leaving the scan running for
+// the next caller.
+func (s *Server) Projects(ctx context.Context) ([]finder.Project, error) {
+	projects, current := s.cachedOrScan()
+	if current == nil {
+		return projects, nil
+	}
+	select {
+	case <-current.done:
+		return current.projects, current.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FindProject returns the project called name, ignoring case. While a scan
+// runs it answers as soon as the scan finds one rather than when the scan
+// ends, so with several projects of that name it is whichever came first.
+// Like Projects it gives up when ctx is done.
+func (s *Server) FindProject(ctx context.Context, name string) (finder.Project, bool, error) {
+	projects, current := s.cachedOrScan()
+	if current == nil {
+		p, ok := findName(projects, name)
+		return p, ok, nil
+	}
+
+	checked := 0
+	for {
+		found, updated := current.progress()
+		if p, ok := findName(found[checked:], name); ok {
+			return p, true, nil
+		}
+		checked = len(found)
+
+		select {
+		case <-updated:
+		case <-current.done:
+			// Everything was found before done was closed
+			found, _ := current.progress()
+			if p, ok := findName(found[checked:], name); ok {
+				return p, true, nil
+			}
+			return finder.Project{}, false, current.err
+		case <-ctx.Done():
+			return finder.Project{}, false, ctx.Err()
+		}
+	}
+}
+
+// findName returns the first of projects called name, ignoring case
+func findName(projects []finder.Project, name string) (finder.Project, bool) {
+	for _, p := range projects {
+		if p.Named(name) {
+			return p, true
+		}
+	}
+	return finder.Project{}, false
+}
+
+// cachedOrScan returns the cached projects while they are fresh, otherwise
+// the scan that will replace them, starting one unless one is running
+func (s *Server) cachedOrScan() ([]finder.Project, *scan) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.projects != nil && time.Since(s.scanned) < scanTTL {
+		s.metrics.cacheHit()
+		return s.projects, nil
+	}
+	if s.scanning != nil {
+		s.metrics.coalesced()
+		return nil, s.scanning
+	}
+
+	s.metrics.cacheMiss()
+	s.scanning = &scan{done: make(chan struct{}), updated: make(chan struct{})}
+	go s.runScan(s.scanning, s.cfg)
+	return nil, s.scanning
+}
+
+// runScan walks the project directories of cfg, publishing projects as they
+// are found. They get their short codes from cfg too, and are left unsorted:
+// clients apply their own order, frecency included.
+func (s *Server) runScan(current *scan, cfg *config.Config) {
+	start := time.Now()
+	codes := cfg.ProjectCodes()
+	current.projects, _, current.err = finder.FindGitProjectsUntil(s.scanCtx, cfg.ProjectDirectories, cfg.Projects, "",
+		func(p finder.Project) bool {
+			p.Code = codes[p.Name]
+			current.add(p)
+			return false
+		})
+	for i := range current.projects {
+		current.projects[i].Code = codes[current.projects[i].Name]
+	}
+	if current.err == nil {
+		s.metrics.scanned(time.Since(start))
+		slog.Debug("scan finished", "projects", len(current.projects), "duration", time.Since(start))
+	}
+
+	s.mu.Lock()
+	// Unless a reload replaced it
+	if s.scanning == current {
+		s.scanning = nil
+		if current.err == nil {
+			s.projects = current.projects
+			s.scanned = time.Now()
+		}
+	}
+	s.mu.Unlock()
+	close(current.done)
+}
+
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	s.metrics.request(r.URL.Path)
+	// Also cancelled when the client goes away
+	ctx, cancel := context.WithTimeout(r.Context(), requestTimeout)
+	defer cancel()
+
+	if name := r.URL.Query().Get("name"); name != "" {
+		project, ok, err := s.FindProject(ctx, name)
+		switch {
+		case err != nil:
+			scanError(w, err)
+		case !ok:
+			http.Error(w, "no project called "+name, http.StatusNotFound)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(project)
+		}
+		return
+	}
+
+	projects, err := s.Projects(ctx)
+	if err != nil {
+		scanError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(projects)
+}
+
+// scanError reports a failed scan, or one that outlasted the request
+func scanError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		http.Error(w, fmt.Sprintf("scan still running after %s", requestTimeout), http.StatusGatewayTimeout)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	s.metrics.request(r.URL.Path)
+
+	if r.Method != http.MethodPost {
+		http.Error(w, "reload needs a POST request", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := s.Reload(); err != nil {
+		slog.Error("config reload failed, keeping the previous config", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	s.metrics.request(r.URL.Path)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	s.metrics.write(w)
+}
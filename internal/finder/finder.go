@@ -1,43 +1,120 @@
 package finder
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strings"
 
 	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/ssh"
+	"github.com/adamflitney/sesh/internal/vcs"
 	"github.com/adamflitney/sesh/internal/zoxide"
 )
 
-// Project represents a Git project
+// Project represents a version-controlled project or a VS Code multi-root
+// workspace
 type Project struct {
-	Name  string
-	Path  string
-	Score float64 // Combined score from zoxide + recency
+	Name    string
+	Path    string
+	Score   float64  // Combined score from zoxide + recency
+	Folders []Folder // Workspace roots, empty for plain Git projects
+	Detail  string   // Extra information the picker shows next to the path
+	Session string   // Fixed tmux session name, instead of one from session_name
+	Code    string   // Short code accepted in place of the name, see SetCodes
 }
 
-// FindGitProjects searches for Git repositories in the given directories
-// Projects are sorted by frecency (frequency + recency) using zoxide scores
-// and the internal recent projects cache
-func FindGitProjects(directories []string) ([]Project, error) {
+// codes holds project short codes by project name, see SetCodes
+var codes map[string]string
+
+// SetCodes sets the short codes found projects get, by project name
+func SetCodes(c map[string]string) {
+	codes = c
+}
+
+// Named reports whether name is the project's name or short code, ignoring case
+func (p Project) Named(name string) bool {
+	return strings.EqualFold(p.Name, name) || p.Code != "" && strings.EqualFold(p.Code, name)
+}
+
+// FindGitProjects searches for Git repositories in the given directories and
+// adds the extra projects listed in the config, returning them in the given
+// sort order (see config.SortOrders), or unsorted for an empty order. Once
+// ctx is done it stops every walk and ssh search and returns ctx's error.
+func FindGitProjects(ctx context.Context, directories []config.ProjectDirectory, extra []config.ExtraProject, order string) ([]Project, error) {
+	projects, _, err := FindGitProjectsUntil(ctx, directories, extra, order, nil)
+	return projects, err
+}
+
+// FindGitProjectsUntil is FindGitProjects, calling found with each project as
+// it turns up, the listed projects first. When found returns true the search
+// stops and that project is returned instead of the list. found may be nil.
+func FindGitProjectsUntil(ctx context.Context, directories []config.ProjectDirectory, extra []config.ExtraProject, order string,
+	found func(Project) bool) ([]Project, *Project, error) {
 	projectsMap := make(map[string]Project) // Use map to avoid duplicates
+	archived, _ := cache.LoadArchived()     // Projects hidden by sesh archive
+	var stopped *Project
 
-	// Directories to skip for performance
-	skipDirs := map[string]bool{
-		"node_modules": true,
-		"vendor":       true,
-		"target":       true,
-		"build":        true,
-		"dist":         true,
-		".next":        true,
-		".cache":       true,
-		"__pycache__":  true,
-		".venv":        true,
-		"venv":         true,
+	// add records a project under key unless it is archived or already
+	// known, and reports whether the search should stop
+	add := func(key string, p Project) bool {
+		if _, ok := projectsMap[key]; ok || archived[p.Path] {
+			return false
+		}
+		p.Code = codes[p.Name]
+		projectsMap[key] = p
+		if found != nil && found(p) {
+			stopped = &p
+			return true
+		}
+		return false
+	}
+
+	// Listed projects come first, so they keep their configured name also
+	// when a project directory contains them
+	for _, p := range extra {
+		if info, err := os.Stat(p.Path); err != nil || !info.IsDir() {
+			fmt.Fprintf(os.Stderr, "Warning: project does not exist: %s\n", p.Path)
+			continue
+		}
+		if add(p.Path, Project{Name: p.Name, Path: p.Path}) {
+			return nil, stopped, nil
+		}
 	}
 
-	for _, dir := range directories {
+	for _, root := range directories {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		dir, maxDepth := root.Path, root.Depth()
+
+		// Remote directories are searched over SSH and their projects
+		// recorded as ssh://host:path
+		if host, remoteDir, ok := root.Remote(); ok {
+			paths, err := ssh.FindProjects(ctx, host, remoteDir, maxDepth, root.Markers, root.SkipPatterns())
+			if ctx.Err() != nil {
+				return nil, nil, ctx.Err()
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				continue
+			}
+			for _, p := range paths {
+				location := config.RemoteLocation(host, p)
+				if root.Excluded(location) {
+					continue
+				}
+				if add(location, Project{Name: path.Base(p), Path: location}) {
+					return nil, stopped, nil
+				}
+			}
+			continue
+		}
+
 		// Check if directory exists
 		if _, err := os.Stat(dir); os.IsNotExist(err) {
 			fmt.Fprintf(os.Stderr, "Warning: directory does not exist: %s\n", dir)
@@ -46,53 +123,143 @@ func FindGitProjects(directories []string) ([]Project, error) {
 
 		// Walk the directory
 		err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
 			if err != nil {
 				// Skip directories we can't read
 				return filepath.SkipDir
 			}
 
-			// Skip common large directories for performance
-			if d.IsDir() && skipDirs[d.Name()] {
+			// Skip large directories such as node_modules for performance
+			if d.IsDir() && path != dir && root.Skips(d.Name()) {
 				return filepath.SkipDir
 			}
 
-			// If this is a .git directory, the parent is a Git project
-			if d.IsDir() && d.Name() == ".git" {
+			// A marker (a VCS directory such as .git unless configured
+			// otherwise) makes its parent a project. VCS markers only count
+			// as directories, as a .git file just links a submodule or
+			// worktree. Checked before the depth limit, which applies to
+			// projects rather than to their markers.
+			if root.IsMarker(d.Name()) && (d.IsDir() || !vcs.IsMarker(d.Name())) {
 				projectPath := filepath.Dir(path)
 				projectName := filepath.Base(projectPath)
 
-				// Store project (map prevents duplicates)
-				projectsMap[projectPath] = Project{
-					Name: projectName,
-					Path: projectPath,
+				if add(projectPath, Project{Name: projectName, Path: projectPath}) {
+					return filepath.SkipAll
 				}
 
-				// Don't descend into .git directory
-				return filepath.SkipDir
+				// Don't descend into marker directories such as .git
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if maxDepth > 0 && pathDepth(dir, path) > maxDepth {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// Excluded directories are pruned along with any projects inside
+			if path != dir && root.Excluded(path) {
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			// VS Code workspace files describe multi-root projects
+			if !d.IsDir() && strings.HasSuffix(d.Name(), workspaceExt) {
+				workspace, err := loadWorkspace(path)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+					return nil
+				}
+				// Keyed by file so a workspace doesn't replace the repo it lives in
+				if add(path, workspace) {
+					return filepath.SkipAll
+				}
 			}
 
 			return nil
 		})
 
+		if ctx.Err() != nil {
+			return nil, nil, ctx.Err()
+		}
 		if err != nil {
-			return nil, fmt.Errorf("error walking directory %s: %w", dir, err)
+			return nil, nil, fmt.Errorf("error walking directory %s: %w", dir, err)
+		}
+		if stopped != nil {
+			return nil, stopped, nil
 		}
 	}
 
-	// Convert map to slice
 	projects := make([]Project, 0, len(projectsMap))
 	for _, project := range projectsMap {
 		projects = append(projects, project)
 	}
 
-	// Apply frecency scoring
-	projects = applyFrecencyScores(projects)
+	if order != "" {
+		Sort(projects, order)
+	}
+	return projects, nil, nil
+}
 
-	return projects, nil
+// Sort orders projects in place: by frecency (frequency + recency, using
+// zoxide scores and the recent projects cache, then the score command), name, the time they were
+// last opened with sesh, or path. Unknown orders fall back to frecency.
+func Sort(projects []Project, order string) {
+	switch order {
+	case "alphabetical":
+		sort.SliceStable(projects, func(i, j int) bool {
+			a, b := strings.ToLower(projects[i].Name), strings.ToLower(projects[j].Name)
+			if a != b {
+				return a < b
+			}
+			return projects[i].Path < projects[j].Path
+		})
+	case "recent":
+		// Projects never opened with sesh follow, alphabetically
+		history, _ := cache.LoadHistory()
+		sort.SliceStable(projects, func(i, j int) bool {
+			a, b := history[projects[i].Path].LastOpened, history[projects[j].Path].LastOpened
+			if !a.Equal(b) {
+				return a.After(b)
+			}
+			return projects[i].Name < projects[j].Name
+		})
+	case "path":
+		sort.SliceStable(projects, func(i, j int) bool {
+			return projects[i].Path < projects[j].Path
+		})
+	default:
+		applyFrecencyScores(projects)
+	}
+}
+
+// pathDepth returns how many levels path is below root
+func pathDepth(root, path string) int {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == "." {
+		return 0
+	}
+	return strings.Count(rel, string(filepath.Separator)) + 1
+}
+
+// ranking weighs zoxide scores against the recent list, see SetRanking
+var ranking = config.DefaultRanking
+
+// SetRanking sets how frecency ordering weighs zoxide scores and the recent list
+func SetRanking(r config.Ranking) {
+	ranking = r
 }
 
 // applyFrecencyScores combines zoxide scores with recent cache for smart ordering
-func applyFrecencyScores(projects []Project) []Project {
+func applyFrecencyScores(projects []Project) {
 	// Get zoxide scores
 	zoxideScores, _ := zoxide.GetScores()
 
@@ -100,7 +267,7 @@ func applyFrecencyScores(projects []Project) []Project {
 	recentProjects, _ := cache.Load()
 	recentPaths := make(map[string]int) // path -> recency rank (1 = most recent)
 	if recentProjects != nil {
-		for i, rp := range recentProjects.GetTop3() {
+		for i, rp := range recentProjects.Top() {
 			recentPaths[rp.Path] = i + 1
 		}
 	}
@@ -112,18 +279,19 @@ func applyFrecencyScores(projects []Project) []Project {
 		// Add zoxide score (frecency from all shell usage)
 		if zoxideScores != nil {
 			if zs, ok := zoxideScores[projects[i].Path]; ok {
-				score += zs
+				score += zs * ranking.ZoxideWeight
 			}
 		}
 
-		// Boost recent projects heavily (they should appear first)
-		// Rank 1 (most recent) gets +10000, rank 2 gets +9000, rank 3 gets +8000
+		// Boost recent projects heavily (by default they come first: rank 1,
+		// the most recent, gets +10000, rank 2 +9000, rank 3 +8000)
 		if rank, ok := recentPaths[projects[i].Path]; ok {
-			score += float64(11000 - (rank * 1000))
+			score += ranking.Boost(rank)
 		}
 
 		projects[i].Score = score
 	}
+	applyScoreCommand(projects)
 
 	// Sort by score (highest first), then by name for ties
 	sort.Slice(projects, func(i, j int) bool {
@@ -132,6 +300,4 @@ func applyFrecencyScores(projects []Project) []Project {
 		}
 		return projects[i].Name < projects[j].Name
 	})
-
-	return projects
 }
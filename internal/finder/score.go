@@ -0,0 +1,86 @@
+package finder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scoreTimeout bounds how long the score command may take, as the picker
+// waits for it
+const scoreTimeout = 2 * time.Second
+
+// scoreCommand adjusts frecency scores, see SetScoreCommand
+var scoreCommand string
+
+// SetScoreCommand sets a shell command that adjusts project scores before
+// frecency sorting. It reads "path<TAB>name<TAB>score" lines on stdin and
+// prints "path<TAB>score" lines for the projects it wants to rescore; the
+// rest keep their scores. An empty command turns the hook off.
+func SetScoreCommand(command string) {
+	scoreCommand = command
+}
+
+// applyScoreCommand runs the score command over projects and updates their
+// scores. A failing command is logged and leaves the scores unchanged.
+func applyScoreCommand(projects []Project) {
+	if scoreCommand == "" || len(projects) == 0 {
+		return
+	}
+
+	scores, err := runScoreCommand(projects)
+	if err != nil {
+		slog.Warn("score command failed", "command", scoreCommand, "error", err)
+		return
+	}
+	for i := range projects {
+		if score, ok := scores[projects[i].Path]; ok {
+			projects[i].Score = score
+		}
+	}
+}
+
+// runScoreCommand returns the scores printed by the score command, by path
+func runScoreCommand(projects []Project) (map[string]float64, error) {
+	var input bytes.Buffer
+	for _, p := range projects {
+		fmt.Fprintf(&input, "%s\t%s\t%g\n", p.Path, p.Name, p.Score)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scoreTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", scoreCommand)
+	cmd.Stdin = &input
+	output, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("timed out after %s", scoreTimeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	scores := make(map[string]float64)
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		i := strings.LastIndexByte(text, '\t')
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected path<TAB>score, got %q", line, text)
+		}
+		score, err := strconv.ParseFloat(strings.TrimSpace(text[i+1:]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid score %q", line, text[i+1:])
+		}
+		scores[text[:i]] = score
+	}
+	return scores, scanner.Err()
+}
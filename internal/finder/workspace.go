@@ -0,0 +1,121 @@
+package finder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// workspaceExt is the extension of VS Code multi-root workspace files
+const workspaceExt = ".code-workspace"
+
+// Folder is a single root of a multi-root workspace
+type Folder struct {
+	Name string
+	Path string
+}
+
+// workspaceFile mirrors the parts of a .code-workspace file sesh uses
+type workspaceFile struct {
+	Folders []struct {
+		Name string `json:"name"`
+		Path string `json:"path"`
+	} `json:"folders"`
+}
+
+// loadWorkspace reads a .code-workspace file and returns a project whose
+// folders are resolved relative to the workspace file
+func loadWorkspace(path string) (Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Project{}, err
+	}
+
+	var ws workspaceFile
+	if err := json.Unmarshal(stripJSONC(data), &ws); err != nil {
+		return Project{}, fmt.Errorf("invalid workspace file %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	project := Project{
+		Name: strings.TrimSuffix(filepath.Base(path), workspaceExt) + " (workspace)",
+		Path: dir,
+	}
+
+	for _, f := range ws.Folders {
+		if f.Path == "" {
+			continue
+		}
+		folderPath := f.Path
+		if !filepath.IsAbs(folderPath) {
+			folderPath = filepath.Join(dir, folderPath)
+		}
+		name := f.Name
+		if name == "" {
+			name = filepath.Base(folderPath)
+		}
+		project.Folders = append(project.Folders, Folder{Name: name, Path: folderPath})
+	}
+
+	if len(project.Folders) == 0 {
+		return Project{}, fmt.Errorf("workspace file %s has no folders", path)
+	}
+	return project, nil
+}
+
+// stripJSONC removes comments and trailing commas from VS Code's JSON with
+// comments format so it can be parsed by encoding/json
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == ',':
+			// Drop the comma if the next significant character closes a list or object
+			j := i + 1
+			for j < len(data) && strings.ContainsRune(" \t\r\n", rune(data[j])) {
+				j++
+			}
+			if j < len(data) && (data[j] == ']' || data[j] == '}') {
+				continue
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
@@ -0,0 +1,62 @@
+package git
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Output runs git in dir and returns its trimmed standard output
+func Output(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// Lines runs git in dir and returns its non-empty output lines
+func Lines(dir string, args ...string) ([]string, error) {
+	output, err := Output(dir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+// DefaultBranch returns the branch origin's HEAD points at, else main or
+// master, whichever exists locally
+func DefaultBranch(dir string) (string, error) {
+	if ref, err := Output(dir, "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD"); err == nil {
+		return strings.TrimPrefix(ref, "origin/"), nil
+	}
+	for _, branch := range []string{"main", "master"} {
+		if _, err := Output(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch); err == nil {
+			return branch, nil
+		}
+	}
+	return "", fmt.Errorf("no default branch found in %s", dir)
+}
+
+// CurrentBranch returns the checked out branch, or an error when HEAD is
+// detached
+func CurrentBranch(dir string) (string, error) {
+	return Output(dir, "symbolic-ref", "--quiet", "--short", "HEAD")
+}
+
+// Dirty reports whether tracked files have uncommitted changes
+func Dirty(dir string) (bool, error) {
+	output, err := Output(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
@@ -0,0 +1,68 @@
+package kube
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/cache"
+)
+
+// IsAvailable checks if kubectl is installed
+func IsAvailable() bool {
+	_, err := exec.LookPath("kubectl")
+	return err == nil
+}
+
+// Contexts returns the names of all contexts in the active kubeconfig
+func Contexts() ([]string, error) {
+	cmd := exec.Command("kubectl", "config", "get-contexts", "-o", "name")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list kubectl contexts: %w", err)
+	}
+
+	var contexts []string
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			contexts = append(contexts, line)
+		}
+	}
+	return contexts, nil
+}
+
+// WriteContextConfig writes a standalone kubeconfig containing only the given
+// context and returns its path. Pointing KUBECONFIG at it pins a session to
+// the context, so switching contexts elsewhere doesn't leak into the session.
+func WriteContextConfig(context string) (string, error) {
+	cmd := exec.Command("kubectl", "config", "view", "--minify", "--flatten", "--context", context)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to export context %s: %w", context, err)
+	}
+
+	cacheDir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	kubeDir := filepath.Join(cacheDir, "kube")
+	if err := os.MkdirAll(kubeDir, 0700); err != nil {
+		return "", err
+	}
+
+	// The file contains credentials, so keep it private
+	path := filepath.Join(kubeDir, safeFileName(context)+".yaml")
+	if err := os.WriteFile(path, output, 0600); err != nil {
+		return "", fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return path, nil
+}
+
+// safeFileName replaces characters that are awkward in file names, such as
+// the slashes and colons found in EKS context ARNs
+func safeFileName(name string) string {
+	return regexp.MustCompile(`[^a-zA-Z0-9_.-]+`).ReplaceAllString(name, "_")
+}
@@ -0,0 +1,138 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+const (
+	logFile    = "sesh.log"
+	maxLogSize = 1 << 20 // Rotate once the log reaches 1 MiB
+	maxBackups = 3       // Keep sesh.log.1 .. sesh.log.3
+)
+
+func init() {
+	// Stay silent until Init is called; slog's default handler writes to stderr
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+}
+
+// level and out are shared by every logger Init installs, so calling it
+// again changes the level without reopening the file
+var (
+	level slog.LevelVar
+	out   *rotatingFile
+)
+
+// Init installs a structured logger writing to sesh.log in the state
+// directory ($XDG_STATE_HOME/sesh or ~/.local/state/sesh).
+// level is one of debug, info, warn, error or off. Later calls, such as
+// the daemon's on reloading its config, only change the level.
+func Init(logLevel string) error {
+	var lvl slog.Level
+	switch strings.ToLower(logLevel) {
+	case "off", "":
+		slog.SetDefault(slog.New(slog.DiscardHandler))
+		return nil
+	case "debug":
+		lvl = slog.LevelDebug
+	case "info":
+		lvl = slog.LevelInfo
+	case "warn", "warning":
+		lvl = slog.LevelWarn
+	case "error":
+		lvl = slog.LevelError
+	default:
+		return fmt.Errorf("invalid log_level %q: expected debug, info, warn, error or off", logLevel)
+	}
+	level.Set(lvl)
+
+	if out == nil {
+		path, err := LogPath()
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), xdg.DirMode()); err != nil {
+			return fmt.Errorf("failed to create log directory: %w", err)
+		}
+		file := &rotatingFile{path: path}
+		if err := file.open(); err != nil {
+			return err
+		}
+		out = file
+	}
+
+	handler := slog.NewTextHandler(out, &slog.HandlerOptions{Level: &level})
+	slog.SetDefault(slog.New(handler).With("pid", os.Getpid()))
+	return nil
+}
+
+// LogPath returns the path of the log file
+func LogPath() (string, error) {
+	stateDir, err := xdg.StateDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(stateDir, logFile), nil
+}
+
+// rotatingFile appends to the log file, rotating it once a write would take
+// it past maxLogSize, so long-running processes like sesh serve rotate too
+type rotatingFile struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+}
+
+// open opens the log file for appending, rotating it first if it is full
+func (r *rotatingFile) open() error {
+	rotate(r.path)
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, xdg.FileMode())
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	r.file = file
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Other sesh processes append to the file too, so its size is asked
+	// for rather than counted. If one of them rotated it already, the new
+	// file is only reopened.
+	if info, err := r.file.Stat(); err == nil && info.Size()+int64(len(p)) > maxLogSize {
+		r.file.Close()
+		if current, err := os.Stat(r.path); err == nil && os.SameFile(info, current) {
+			shift(r.path)
+		}
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	return r.file.Write(p)
+}
+
+// rotate shifts sesh.log to sesh.log.1 (and older backups along) once it
+// has reached maxLogSize
+func rotate(path string) {
+	info, err := os.Stat(path)
+	if err != nil || info.Size() < maxLogSize {
+		return
+	}
+	shift(path)
+}
+
+// shift moves sesh.log to sesh.log.1, and older backups along
+func shift(path string) {
+	for i := maxBackups - 1; i >= 1; i-- {
+		_ = os.Rename(fmt.Sprintf("%s.%d", path, i), fmt.Sprintf("%s.%d", path, i+1))
+	}
+	_ = os.Rename(path, path+".1")
+}
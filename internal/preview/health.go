@@ -0,0 +1,37 @@
+package preview
+
+import (
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/git"
+)
+
+// Health summarises signs of unfinished work in a project
+type Health struct {
+	Todos   int
+	Fixmes  int
+	Stashes int
+}
+
+// LoadHealth counts TODO/FIXME markers in tracked files and open stashes.
+// Projects that aren't Git repositories report zero for everything.
+func LoadHealth(path string) Health {
+	var h Health
+
+	// git grep only searches tracked files, which keeps this quick and skips
+	// dependencies and build output
+	markers, _ := git.Lines(path, "grep", "-I", "-o", "-h", "-w", "-E", "TODO|FIXME")
+	for _, m := range markers {
+		switch strings.TrimSpace(m) {
+		case "TODO":
+			h.Todos++
+		case "FIXME":
+			h.Fixmes++
+		}
+	}
+
+	stashes, _ := git.Lines(path, "stash", "list")
+	h.Stashes = len(stashes)
+
+	return h
+}
@@ -0,0 +1,60 @@
+package preview
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/git"
+)
+
+// sizeTTL is how long a computed size is reused before it is measured again
+const sizeTTL = 24 * time.Hour
+
+// sizesMu serialises updates to the sizes cache file, since previews are
+// loaded concurrently
+var sizesMu sync.Mutex
+
+// LoadSize returns the approximate size of a project's working tree: the
+// files git would consider, i.e. tracked plus untracked files that aren't
+// ignored. Results are cached for a day.
+func LoadSize(path string) (cache.RepoSize, error) {
+	sizesMu.Lock()
+	sizes, _ := cache.LoadSizes()
+	sizesMu.Unlock()
+	if size, ok := sizes[path]; ok && time.Since(size.Computed) < sizeTTL {
+		return size, nil
+	}
+
+	// -z keeps unusual file names unquoted
+	output, err := git.Output(path, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return cache.RepoSize{}, fmt.Errorf("failed to list files in %s: %w", path, err)
+	}
+
+	size := cache.RepoSize{Computed: time.Now()}
+	for _, f := range strings.Split(output, "\x00") {
+		if f == "" {
+			continue
+		}
+		// Deleted but still tracked files are listed too
+		info, err := os.Lstat(filepath.Join(path, f))
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		size.Files++
+		size.Bytes += info.Size()
+	}
+
+	sizesMu.Lock()
+	defer sizesMu.Unlock()
+	// Reload so sizes saved by other previews in the meantime are kept
+	sizes, _ = cache.LoadSizes()
+	sizes[path] = size
+	_ = cache.SaveSizes(sizes)
+	return size, nil
+}
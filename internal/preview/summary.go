@@ -0,0 +1,136 @@
+package preview
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/vcs"
+)
+
+// Summary describes what a project is. Projects with a README are described
+// by it; for the rest the summary is pieced together from the languages of
+// tracked files and the latest commits, in whichever version control system
+// the project uses.
+type Summary struct {
+	Description string     // First line of prose from the README
+	Languages   []Language // Top languages by tracked file count, largest first
+	Commits     []string   // Subjects of the latest commits
+}
+
+// Language is a share of a project's tracked source files
+type Language struct {
+	Name    string
+	Percent int
+}
+
+// languages maps file extensions to language names
+var languages = map[string]string{
+	".go": "Go", ".rs": "Rust", ".py": "Python", ".rb": "Ruby", ".java": "Java",
+	".kt": "Kotlin", ".swift": "Swift", ".c": "C", ".h": "C", ".cc": "C++",
+	".cpp": "C++", ".hpp": "C++", ".cs": "C#", ".js": "JavaScript",
+	".jsx": "JavaScript", ".mjs": "JavaScript", ".ts": "TypeScript",
+	".tsx": "TypeScript", ".lua": "Lua", ".sh": "Shell", ".zsh": "Shell",
+	".php": "PHP", ".ex": "Elixir", ".exs": "Elixir", ".hs": "Haskell",
+	".scala": "Scala", ".nix": "Nix", ".tf": "Terraform", ".vue": "Vue",
+	".svelte": "Svelte", ".dart": "Dart", ".zig": "Zig", ".sql": "SQL",
+	".html": "HTML", ".css": "CSS", ".scss": "CSS",
+}
+
+// LoadSummary builds the summary for the project at path
+func LoadSummary(path string) Summary {
+	if description := readmeDescription(path); description != "" {
+		return Summary{Description: description}
+	}
+
+	var s Summary
+	repo, ok := vcs.Detect(path)
+	if !ok {
+		return s
+	}
+	if files, err := repo.Files(path); err == nil {
+		s.Languages = languageBreakdown(files, 3)
+	}
+	s.Commits, _ = repo.Log(path, 3)
+	return s
+}
+
+// readmeDescription returns the first line of prose in the project's README,
+// skipping headings, badges and HTML
+func readmeDescription(path string) string {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return ""
+	}
+
+	for _, e := range entries {
+		name := strings.ToLower(e.Name())
+		if e.IsDir() || !strings.HasPrefix(name, "readme") {
+			continue
+		}
+
+		if line := firstProseLine(filepath.Join(path, e.Name())); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// firstProseLine returns the first line of a Markdown or text file that
+// isn't markup
+func firstProseLine(file string) string {
+	f, err := os.Open(file)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.ContainsAny(line[:1], "#=-<![|`>") {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// languageBreakdown returns the top n languages among files
+func languageBreakdown(files []string, n int) []Language {
+	counts := make(map[string]int)
+	total := 0
+	for _, f := range files {
+		if lang, ok := languages[strings.ToLower(filepath.Ext(f))]; ok {
+			counts[lang]++
+			total++
+		}
+	}
+
+	breakdown := make([]Language, 0, len(counts))
+	for name, count := range counts {
+		breakdown = append(breakdown, Language{Name: name, Percent: count * 100 / total})
+	}
+	sort.Slice(breakdown, func(i, j int) bool {
+		if breakdown[i].Percent != breakdown[j].Percent {
+			return breakdown[i].Percent > breakdown[j].Percent
+		}
+		return breakdown[i].Name < breakdown[j].Name
+	})
+	if len(breakdown) > n {
+		breakdown = breakdown[:n]
+	}
+	return breakdown
+}
+
+// LanguageString formats the language breakdown, e.g. "Go 80% • Shell 20%"
+func (s Summary) LanguageString() string {
+	parts := make([]string, len(s.Languages))
+	for i, l := range s.Languages {
+		parts[i] = fmt.Sprintf("%s %d%%", l.Name, l.Percent)
+	}
+	return strings.Join(parts, " • ")
+}
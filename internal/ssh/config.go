@@ -0,0 +1,122 @@
+package ssh
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Host represents a host alias defined in the SSH client config
+type Host struct {
+	Name     string
+	HostName string // Value of HostName, empty if not set
+	User     string
+}
+
+// Address returns a user@hostname description of the host for display
+func (h Host) Address() string {
+	addr := h.HostName
+	if addr == "" {
+		addr = h.Name
+	}
+	if h.User != "" {
+		addr = h.User + "@" + addr
+	}
+	return addr
+}
+
+// LoadHosts returns the concrete (non-wildcard) hosts from ~/.ssh/config,
+// following Include directives
+func LoadHosts() ([]Host, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var hosts []Host
+	seen := make(map[string]bool)
+	if err := parseConfig(filepath.Join(home, ".ssh", "config"), home, &hosts, seen, 0); err != nil {
+		return nil, err
+	}
+	return hosts, nil
+}
+
+// parseConfig reads one SSH config file, appending hosts it defines
+func parseConfig(path, home string, hosts *[]Host, seen map[string]bool, depth int) error {
+	// Guard against Include loops
+	if depth > 8 {
+		return nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) && depth > 0 {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	// Indexes into hosts for the current Host block
+	var current []int
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value := splitDirective(line)
+		switch strings.ToLower(key) {
+		case "host":
+			current = nil
+			for _, name := range strings.Fields(value) {
+				if strings.ContainsAny(name, "*?!") || seen[name] {
+					continue
+				}
+				seen[name] = true
+				*hosts = append(*hosts, Host{Name: name})
+				current = append(current, len(*hosts)-1)
+			}
+		case "match":
+			current = nil
+		case "hostname":
+			for _, i := range current {
+				(*hosts)[i].HostName = value
+			}
+		case "user":
+			for _, i := range current {
+				(*hosts)[i].User = value
+			}
+		case "include":
+			for _, pattern := range strings.Fields(value) {
+				if strings.HasPrefix(pattern, "~/") {
+					pattern = filepath.Join(home, pattern[2:])
+				} else if !filepath.IsAbs(pattern) {
+					pattern = filepath.Join(home, ".ssh", pattern)
+				}
+				matches, _ := filepath.Glob(pattern)
+				for _, m := range matches {
+					if err := parseConfig(m, home, hosts, seen, depth+1); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	return scanner.Err()
+}
+
+// splitDirective splits "Key value" or "Key=value" into its parts
+func splitDirective(line string) (string, string) {
+	idx := strings.IndexAny(line, " \t=")
+	if idx < 0 {
+		return line, ""
+	}
+	key := line[:idx]
+	value := strings.TrimLeft(line[idx:], " \t=")
+	return key, strings.Trim(value, `"`)
+}
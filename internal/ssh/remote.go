@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/vcs"
+)
+
+// missingDirStatus is the exit status of the remote scan when the directory
+// doesn't exist, told apart from ssh's own 255
+const missingDirStatus = 3
+
+// options keep ssh from prompting for passwords or host keys, which would
+// hang a scan with nobody to answer, and bound how long an unreachable host
+// can hold one up
+var options = []string{"-o", "BatchMode=yes", "-o", "ConnectTimeout=5"}
+
+// FindProjects lists the projects below dir on an SSH host using find: the
+// parents of entries named like one of markers (VCS markers such as .git only
+// counting when they are directories), no more than maxDepth levels down (0 for unlimited),
+// without descending into directories matching skipDirs. The ssh command is
+// killed once ctx is done.
+func FindProjects(ctx context.Context, host, dir string, maxDepth int, markers, skipDirs []string) ([]string, error) {
+	root := remotePath(dir)
+	script := fmt.Sprintf("[ -d %s ] || exit %d; find %s -mindepth 1", root, missingDirStatus, root)
+	if maxDepth > 0 {
+		// Markers sit one level below their project
+		script += " -maxdepth " + strconv.Itoa(maxDepth+1)
+	}
+	if len(skipDirs) > 0 {
+		script += ` -type d \( ` + nameTests(skipDirs) + ` \) -prune -o`
+	}
+	var tests []string
+	for _, m := range markers {
+		if vcs.IsMarker(m) {
+			tests = append(tests, `\( -name `+quote(m)+` -type d \)`)
+		} else {
+			tests = append(tests, "-name "+quote(m))
+		}
+	}
+	script += ` \( ` + strings.Join(tests, " -o ") + ` \) -prune -print 2>/dev/null; exit 0`
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "ssh", append(append([]string{}, options...), host, script)...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == missingDirStatus {
+			return nil, fmt.Errorf("directory does not exist on %s: %s", host, dir)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("listing projects on %s: %s", host, msg)
+		}
+		return nil, fmt.Errorf("listing projects on %s: %w", host, err)
+	}
+
+	var projects []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		if line == "" {
+			continue
+		}
+		project := path.Dir(line)
+		if !seen[project] {
+			seen[project] = true
+			projects = append(projects, project)
+		}
+	}
+	return projects, nil
+}
+
+// nameTests joins find -name tests for patterns with -o
+func nameTests(patterns []string) string {
+	tests := make([]string, len(patterns))
+	for i, p := range patterns {
+		tests[i] = "-name " + quote(p)
+	}
+	return strings.Join(tests, " -o ")
+}
+
+// AttachCommand returns the shell command that connects to host and attaches
+// to the tmux session of the given name there, creating it in dir if needed
+func AttachCommand(host, session, dir string) string {
+	remote := "tmux new-session -A -s " + quote(session) + " -c " + remotePath(dir)
+	return "ssh -t " + quote(host) + " " + quote(remote)
+}
+
+// remotePath quotes a path for the remote shell, leaving a leading ~ to be
+// expanded there
+func remotePath(p string) string {
+	if p == "~" {
+		return `"$HOME"`
+	}
+	if rest, ok := strings.CutPrefix(p, "~/"); ok {
+		return `"$HOME"/` + quote(rest)
+	}
+	return quote(p)
+}
+
+// quote quotes s for a POSIX shell
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
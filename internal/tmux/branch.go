@@ -0,0 +1,35 @@
+package tmux
+
+import (
+	"log/slog"
+	"path/filepath"
+
+	"github.com/adamflitney/sesh/internal/git"
+)
+
+// checkoutDefaultBranch puts a repository left on another branch back on its
+// default branch before its session starts. Repositories with uncommitted
+// changes or a detached HEAD are left as they are.
+func checkoutDefaultBranch(path string) {
+	branch, err := git.DefaultBranch(path)
+	if err != nil {
+		slog.Debug("not checking out the default branch", "path", path, "error", err)
+		return
+	}
+	current, err := git.CurrentBranch(path)
+	if err != nil || current == branch {
+		return
+	}
+
+	name := filepath.Base(path)
+	if dirty, err := git.Dirty(path); err != nil || dirty {
+		notify("Staying on %s in %s: it has uncommitted changes", current, name)
+		return
+	}
+	if _, err := git.Output(path, "checkout", "--quiet", branch); err != nil {
+		notify("Failed to check out %s in %s: %v", branch, name, err)
+		return
+	}
+	slog.Info("checked out default branch", "path", path, "branch", branch, "previous", current)
+	notify("Checked out %s in %s (was on %s)", branch, name, current)
+}
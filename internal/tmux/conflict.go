@@ -0,0 +1,98 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConflictChoice is what to do when a project's session name is taken by a
+// session that belongs to another directory, e.g. after a project was
+// renamed or moved
+type ConflictChoice int
+
+const (
+	ConflictAttach   ConflictChoice = iota // Use the existing session anyway
+	ConflictRecreate                       // Kill it and create a session for the project
+	ConflictRename                         // Rename it out of the way and create a session
+)
+
+// conflictPrompt asks what to do about a conflicting session, nil when sesh
+// can't ask
+var conflictPrompt func(session, sessionPath, projectPath string) (ConflictChoice, error)
+
+// SetConflictPrompt sets how to ask what to do when an existing session
+// belongs to a different directory than the project being opened. Without
+// one, sesh warns and attaches.
+func SetConflictPrompt(prompt func(session, sessionPath, projectPath string) (ConflictChoice, error)) {
+	conflictPrompt = prompt
+}
+
+// resolveConflict checks that an existing session belongs to projectPath
+// and, if it doesn't, handles it as the user chooses. It reports whether
+// the session is still there to attach to. The caller checks that a session
+// of exactly that name exists, see SessionExists.
+func resolveConflict(sessionName, projectPath string) (bool, error) {
+	current := sessionPath(sessionName)
+	if projectPath == "" || current == "" || samePath(current, projectPath) {
+		return true, nil
+	}
+
+	if conflictPrompt == nil {
+		fmt.Fprintf(os.Stderr, "Warning: session '%s' belongs to %s, not %s\n", sessionName, current, projectPath)
+		return true, nil
+	}
+	choice, err := conflictPrompt(sessionName, current, projectPath)
+	if err != nil {
+		return false, err
+	}
+
+	switch choice {
+	case ConflictRecreate:
+		snapshot := cfg != nil && cfg.SnapshotOnKill
+		if err := KillSession(sessionName, snapshot); err != nil {
+			return false, err
+		}
+		return false, nil
+	case ConflictRename:
+		name, err := freeSessionName(sessionName + "-old")
+		if err != nil {
+			return false, err
+		}
+		if _, err := RenameSession(sessionName, name); err != nil {
+			return false, err
+		}
+		notify("Renamed existing session to '%s'", name)
+		return false, nil
+	default:
+		return true, nil
+	}
+}
+
+// sessionPath returns the directory a session belongs to: the project sesh
+// recorded on it, else the directory tmux started it in
+func sessionPath(sessionName string) string {
+	format := "#{" + ProjectOption + "}" + fieldSep + "#{session_path}"
+	output, err := tmuxCmd("display-message", "-p", "-t", sessionTarget(sessionName), format).Output()
+	if err != nil {
+		return ""
+	}
+	project, start, _ := strings.Cut(strings.TrimSpace(string(output)), fieldSep)
+	if project != "" {
+		return project
+	}
+	return start
+}
+
+// samePath reports whether two paths name the same directory, following
+// symlinks where they resolve
+func samePath(a, b string) bool {
+	if resolved, err := filepath.EvalSymlinks(a); err == nil {
+		a = resolved
+	}
+	if resolved, err := filepath.EvalSymlinks(b); err == nil {
+		b = resolved
+	}
+	return filepath.Clean(a) == filepath.Clean(b)
+}
@@ -0,0 +1,76 @@
+package tmux
+
+import (
+	"context"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/finder"
+)
+
+// dedupeMode returns the configured dedupe_sessions policy
+func dedupeMode() string {
+	if cfg == nil || cfg.DedupeSessions == "" {
+		return config.DedupeModes[0]
+	}
+	return cfg.DedupeSessions
+}
+
+// matchedSession returns the name of the session to open for project under
+// the dedupe_sessions policy, whether or not it exists yet. Projects with a
+// fixed session name, such as ssh hosts, always get that name, as they
+// share their directory with each other.
+func matchedSession(project finder.Project) (string, error) {
+	name := SessionName(project)
+	mode := dedupeMode()
+	if mode == "name" || project.Session != "" {
+		return name, nil
+	}
+
+	sessions, err := ListSessionInfo(context.Background())
+	if err != nil {
+		// No server yet, so nothing to reuse
+		return name, nil
+	}
+	profile := config.ActiveProfile()
+	belongs := func(s SessionInfo) bool {
+		if s.Project == "" || !samePath(s.Project, project.Path) {
+			return false
+		}
+		return mode == "path" || strings.EqualFold(s.Profile, profile)
+	}
+
+	// The session with the project's own name wins over other matches
+	var named *SessionInfo
+	for i := range sessions {
+		if sessions[i].Name == name {
+			named = &sessions[i]
+		}
+	}
+	if named != nil && belongs(*named) {
+		return name, nil
+	}
+	for _, s := range sessions {
+		if belongs(s) {
+			return s.Name, nil
+		}
+	}
+
+	// With sessions kept per profile, a session another profile made for
+	// the project leaves this one a name of its own, api-2 for api
+	if mode == "off" && named != nil && named.Project != "" && samePath(named.Project, project.Path) {
+		return freeSessionName(name)
+	}
+	return name, nil
+}
+
+// ProjectSession returns the name of the existing session project opens
+// under the dedupe_sessions policy, reporting false if it has none
+func ProjectSession(project finder.Project) (string, bool) {
+	name, err := matchedSession(project)
+	if err != nil {
+		return "", false
+	}
+	exists, err := SessionExists(name)
+	return name, err == nil && exists
+}
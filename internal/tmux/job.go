@@ -0,0 +1,65 @@
+package tmux
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// JobState describes the state of a job session
+type JobState int
+
+const (
+	JobRunning JobState = iota
+	JobExited
+	JobGone // The session no longer exists
+)
+
+// StartJob creates a detached session running command in dir. The pane is
+// kept after the command exits so its output and exit status can be checked.
+func StartJob(sessionName, dir, command string) error {
+	exists, err := SessionExists(sessionName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("session %s already exists", sessionName)
+	}
+
+	// Start with a shell, enable remain-on-exit, then swap in the command so
+	// even commands that exit immediately keep their pane
+	cmd := tmuxCmd("new-session", "-d", "-s", sessionName, "-c", dir, "-n", "job")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to create job session: %w", err)
+	}
+
+	cmd = tmuxCmd("set-option", "-t", sessionTarget(sessionName), "remain-on-exit", "on")
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to configure job session: %w", err)
+	}
+
+	cmd = tmuxCmd("respawn-pane", "-k", "-t", sessionTarget(sessionName)+"job", "-c", dir, command)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to start job: %w", err)
+	}
+	slog.Info("started job", "session", sessionName, "dir", dir, "command", command)
+	return nil
+}
+
+// JobStatus returns the state of a job session and, once exited, its exit code
+func JobStatus(sessionName string) (JobState, int) {
+	cmd := tmuxCmd("list-panes", "-t", sessionTarget(sessionName)+"job", "-F", "#{pane_dead}"+fieldSep+"#{pane_dead_status}")
+	output, err := cmd.Output()
+	if err != nil {
+		return JobGone, 0
+	}
+
+	parts := strings.SplitN(strings.TrimSpace(string(output)), fieldSep, 2)
+	if len(parts) != 2 || parts[0] != "1" {
+		return JobRunning, 0
+	}
+
+	code := 0
+	fmt.Sscanf(parts[1], "%d", &code)
+	return JobExited, code
+}
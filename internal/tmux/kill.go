@@ -0,0 +1,154 @@
+package tmux
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+// KillSession kills a tmux session. When snapshot is true the scrollback of
+// every pane is saved to the snapshots cache directory first. The session's
+// project and windows are remembered so RestoreLastKilled can undo the kill.
+func KillSession(sessionName string, snapshot bool) error {
+	// Recording is best effort; failing to remember shouldn't block the kill
+	if killed, err := describeSession(sessionName); err == nil {
+		_ = cache.SaveLastKilled(*killed)
+	}
+
+	if snapshot {
+		if _, err := SnapshotPanes(sessionName); err != nil {
+			return fmt.Errorf("failed to snapshot session %s: %w", sessionName, err)
+		}
+	}
+
+	// Taken first, as the server exits along with its last session
+	autoSnapshot(sessionName)
+
+	cmd := tmuxCmd("kill-session", "-t", sessionTarget(sessionName))
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to kill session %s: %w", sessionName, err)
+	}
+	slog.Info("killed session", "session", sessionName, "snapshot", snapshot)
+	return nil
+}
+
+// SnapshotPanes writes the full scrollback of every pane in a session to
+// ~/.cache/sesh/snapshots/<session>-<timestamp>.txt and returns the file path
+func SnapshotPanes(sessionName string) (string, error) {
+	cmd := tmuxCmd("list-panes", "-s", "-t", sessionTarget(sessionName), "-F", "#{pane_id}"+fieldSep+"#{window_name}.#{pane_index}")
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to list panes: %w", err)
+	}
+
+	var s strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, fieldSep, 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		// -S - starts the capture at the beginning of the history
+		cmd := tmuxCmd("capture-pane", "-p", "-J", "-S", "-", "-t", parts[0])
+		contents, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to capture pane %s: %w", parts[1], err)
+		}
+
+		fmt.Fprintf(&s, "===== %s =====\n", parts[1])
+		s.WriteString(strings.TrimRight(string(contents), "\n"))
+		s.WriteString("\n\n")
+	}
+
+	cacheDir, err := cache.Dir()
+	if err != nil {
+		return "", err
+	}
+	snapshotDir := filepath.Join(cacheDir, "snapshots")
+	if err := os.MkdirAll(snapshotDir, xdg.DirMode()); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s.txt", sessionName, time.Now().Format("20060102-150405"))
+	path := filepath.Join(snapshotDir, name)
+	if err := os.WriteFile(path, []byte(s.String()), xdg.FileMode()); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// describeSession captures what is needed to recreate a session
+func describeSession(sessionName string) (*cache.KilledSession, error) {
+	cmd := tmuxCmd("display-message", "-p", "-t", sessionTarget(sessionName), "#{"+ProjectOption+"}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	killed := &cache.KilledSession{
+		Session:  sessionName,
+		Project:  strings.TrimSpace(string(output)),
+		KilledAt: time.Now(),
+	}
+
+	cmd = tmuxCmd("list-windows", "-t", sessionTarget(sessionName), "-F", "#{window_name}"+fieldSep+"#{pane_current_path}")
+	output, err = cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, fieldSep, 2)
+		if len(parts) == 2 {
+			killed.Windows = append(killed.Windows, cache.KilledWindow{Name: parts[0], Path: parts[1]})
+		}
+	}
+	return killed, nil
+}
+
+// RestoreLastKilled recreates the session most recently killed by sesh and
+// switches or attaches to it. Sessions sesh created for a project get the
+// project's layout again, commands, panes, options and environment
+// included; other sessions get their windows back as plain shells.
+func RestoreLastKilled() error {
+	killed, err := cache.LoadLastKilled()
+	if err != nil {
+		return err
+	}
+	if killed == nil || len(killed.Windows) == 0 {
+		return fmt.Errorf("no killed session to restore")
+	}
+
+	exists, err := SessionExists(killed.Session)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("session %s already exists", killed.Session)
+	}
+
+	var project finder.Project
+	var layout Layout
+	if killed.Project != "" {
+		project = finder.Project{Name: filepath.Base(killed.Project), Path: killed.Project, Session: killed.Session}
+		if layout, err = layoutFor(project); err != nil {
+			return err
+		}
+		// Undoing a kill shouldn't move the project to another branch
+		layout.CheckoutDefault = false
+	} else {
+		project = finder.Project{Name: killed.Session, Path: killed.Windows[0].Path, Session: killed.Session}
+		for _, w := range killed.Windows {
+			layout.Windows = append(layout.Windows, Window{Name: w.Name, Dir: w.Path})
+		}
+	}
+
+	// Clear first so a failed attach doesn't leave the record to be restored twice
+	_ = cache.ClearLastKilled()
+	return OpenSession(project, layout)
+}
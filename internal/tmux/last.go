@@ -0,0 +1,84 @@
+package tmux
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/cache"
+)
+
+// recordSwitch notes in the session history that a client moved from one
+// session to target, so sesh last can take it back
+func recordSwitch(from, target string) {
+	history, err := cache.LoadSessionHistory()
+	if err != nil {
+		slog.Debug("failed to load session history", "error", err)
+		return
+	}
+
+	if from == "" {
+		// Attaching from outside tmux leaves wherever sesh last went
+		from = history.Current
+	}
+	if from != "" && from != target {
+		history.Previous = from
+	}
+	history.Current = target
+	if err := history.Save(); err != nil {
+		slog.Debug("failed to save session history", "error", err)
+	}
+}
+
+// recordRename follows a renamed session in the session history, so sesh
+// last still finds it
+func recordRename(oldName, newName string) {
+	history, err := cache.LoadSessionHistory()
+	if err != nil || !history.Rename(oldName, newName) {
+		return
+	}
+	if err := history.Save(); err != nil {
+		slog.Debug("failed to save session history", "error", err)
+	}
+}
+
+// PreviousSession returns the session sesh last switched away from. If that
+// is where the client already is, for instance after switching there with
+// tmux itself, it is the one sesh switched to instead.
+func PreviousSession() (string, error) {
+	history, err := cache.LoadSessionHistory()
+	if err != nil {
+		return "", err
+	}
+
+	target := history.Previous
+	if target == "" || target == clientSession(os.Getenv("SESH_TARGET_CLIENT")) {
+		target = history.Current
+	}
+	if target == "" {
+		return "", fmt.Errorf("no previous session: sesh hasn't switched sessions yet")
+	}
+	if exists, _ := SessionExists(target); !exists {
+		return "", fmt.Errorf("previous session %s no longer exists", target)
+	}
+	return target, nil
+}
+
+// clientSession returns the session the client on the given tty is
+// attached to, that of the current client if client is empty. Unlike
+// CurrentSession it follows the client, not the newest session, so a session
+// sesh just created doesn't count as where the client is.
+func clientSession(client string) string {
+	args := []string{"display-message", "-p"}
+	if client != "" {
+		args = append(args, "-c", client)
+	} else if os.Getenv("TMUX") == "" {
+		return ""
+	}
+	output, err := clientCmd(append(args, "#{client_session}")...).Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
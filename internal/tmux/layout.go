@@ -0,0 +1,622 @@
+package tmux
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/ssh"
+)
+
+// cfg is the user configuration applied to new sessions, nil for built-in defaults
+var cfg *config.Config
+
+// SetConfig sets the configuration used when creating sessions
+func SetConfig(c *config.Config) {
+	cfg = c
+}
+
+// Layout describes the windows and environment of a new session
+type Layout struct {
+	Windows []Window
+	Env     map[string]string // Session environment inherited by every window
+	Options map[string]string // tmux session options set once the session exists
+
+	// CheckoutDefault checks out the project's default branch before the
+	// session is created, see checkoutDefaultBranch
+	CheckoutDefault bool
+
+	// Startup holds commands run in a startup window once the session is
+	// created, see startupWindow
+	Startup []string
+}
+
+// startupWindowName names the window the project's startup commands run in
+const startupWindowName = "startup"
+
+// Window describes a single tmux window created as part of a session layout
+type Window struct {
+	Name    string
+	Command string
+	Dir     string            // Working directory, defaults to the project path
+	Env     map[string]string // Environment for this window only
+	Panes   []Pane            // Panes split off the window, see splitPanes
+	Layout  string            // tmux layout arranging the panes, if any
+}
+
+// Pane describes a pane split off a layout window
+type Pane struct {
+	Command string
+	Dir     string // Working directory, defaults to the window's
+	Split   string // vertical or horizontal, see config.PaneSplits
+	Size    string // Percentage such as 30%, or lines or columns
+}
+
+// DefaultWindows returns the layout used for new sessions: the editor,
+// opencode and zsh
+func DefaultWindows() []Window {
+	editor := editorCommand()
+	name := filepath.Base(strings.Fields(editor)[0])
+	if name == "nvim" {
+		name = "neovim"
+	}
+
+	return []Window{
+		{Name: name, Command: editor + " ."},
+		// Start with --port flag so opencode.nvim can connect to it
+		{Name: "opencode", Command: "opencode --port 0 ."},
+		{Name: "zsh"},
+	}
+}
+
+// editorCommand returns the editor projects are opened in: the editor config
+// key, then $EDITOR, then nvim
+func editorCommand() string {
+	if cfg != nil && strings.TrimSpace(cfg.Editor) != "" {
+		return strings.TrimSpace(cfg.Editor)
+	}
+	if editor := strings.TrimSpace(os.Getenv("EDITOR")); editor != "" {
+		return editor
+	}
+	return "nvim"
+}
+
+// workspaceWindows returns a layout with one window per workspace folder
+func workspaceWindows(project finder.Project) []Window {
+	windows := make([]Window, 0, len(project.Folders))
+	for _, f := range project.Folders {
+		windows = append(windows, Window{Name: f.Name, Dir: f.Path})
+	}
+	return windows
+}
+
+// remoteLayout returns the layout of a project on an SSH host: a single
+// window, in the local home directory, attached to a session of the same
+// name on the host
+func remoteLayout(project finder.Project, host, dir string) (Layout, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return Layout{}, err
+	}
+	return Layout{Windows: []Window{
+		{Name: "ssh", Command: ssh.AttachCommand(host, SessionName(project), dir), Dir: home},
+	}}, nil
+}
+
+// layoutFor returns the layout for a project. Windows come from the
+// project's .sesh.yaml if it defines any, then from the workspace folders of
+// multi-root workspaces, then from the session template for the project,
+// then from the global config or the built-in layout. Projects on SSH hosts
+// always get remoteLayout.
+func layoutFor(project finder.Project) (Layout, error) {
+	if host, dir, ok := config.ParseRemote(project.Path); ok {
+		return remoteLayout(project, host, dir)
+	}
+
+	pc, tmpl, tmplName, err := projectTemplate(project)
+	if err != nil {
+		return Layout{}, err
+	}
+
+	checkout := cfg != nil && cfg.CheckoutDefaultOnCreate
+	if pc != nil && pc.CheckoutDefaultOnCreate != nil {
+		checkout = *pc.CheckoutDefaultOnCreate
+	}
+
+	env, err := templateEnv(pc, tmpl)
+	if err != nil {
+		return Layout{}, err
+	}
+	var startup []string
+	if pc != nil {
+		for _, command := range pc.Startup {
+			expanded, err := expandVars(command)
+			if err != nil {
+				return Layout{}, fmt.Errorf("startup: %w", err)
+			}
+			startup = append(startup, expanded)
+		}
+	}
+
+	var windows []config.Window
+	var source string
+	switch {
+	case pc != nil && len(pc.Windows) > 0:
+		windows, source = pc.Windows, pc.Path
+	case len(project.Folders) > 0:
+		return Layout{Windows: workspaceWindows(project), Env: env, Options: tmpl.Options, Startup: startup}, nil
+	case len(tmpl.Windows) > 0:
+		slog.Debug("using session template", "template", tmplName, "project", project.Name)
+		windows, source = tmpl.Windows, "template "+tmplName
+	case cfg != nil && len(cfg.Windows) > 0:
+		windows, source = cfg.Windows, "windows config"
+	default:
+		return Layout{Windows: DefaultWindows(), Env: env, Options: tmpl.Options, CheckoutDefault: checkout, Startup: startup}, nil
+	}
+
+	var templates map[string]config.WindowTemplate
+	if cfg != nil {
+		templates = cfg.WindowTemplates
+	}
+	windows, err = config.ResolveWindows(windows, templates)
+	if err != nil {
+		return Layout{}, fmt.Errorf("invalid %s: %w", source, err)
+	}
+	windows, err = config.FilterWindows(windows, project.Path)
+	if err != nil {
+		return Layout{}, fmt.Errorf("invalid %s: %w", source, err)
+	}
+	if len(windows) == 0 {
+		return Layout{}, fmt.Errorf("no windows in the layout apply to %s", project.Name)
+	}
+	if err := expandWindows(windows); err != nil {
+		return Layout{}, err
+	}
+
+	layout, err := convertWindows(windows, project.Path)
+	if err != nil {
+		return Layout{}, err
+	}
+	layout.Env = env
+	layout.Options = tmpl.Options
+	layout.CheckoutDefault = checkout
+	layout.Startup = startup
+	return layout, nil
+}
+
+// projectTemplate loads a project's .sesh.yaml, nil if it has none, and
+// picks its session template, if any, with the template's name
+func projectTemplate(project finder.Project) (*config.ProjectConfig, config.SessionTemplate, string, error) {
+	pc, err := config.LoadProjectConfig(project.Path)
+	if err != nil {
+		return nil, config.SessionTemplate{}, "", err
+	}
+	if cfg == nil {
+		return pc, config.SessionTemplate{}, "", nil
+	}
+
+	explicit := ""
+	if pc != nil {
+		slog.Debug("using project config", "path", pc.Path)
+		explicit = pc.Template
+	}
+	tmplName, err := cfg.TemplateFor(explicit, project.Name, project.Path)
+	if err != nil && pc != nil {
+		return nil, config.SessionTemplate{}, "", fmt.Errorf("%s: %w", pc.Path, err)
+	} else if err != nil {
+		return nil, config.SessionTemplate{}, "", err
+	}
+	tmpl, _ := cfg.Template(tmplName)
+	return pc, tmpl, tmplName, nil
+}
+
+// templateEnv returns the session environment of a project: its template's
+// variables, overridden by those in its .sesh.yaml
+func templateEnv(pc *config.ProjectConfig, tmpl config.SessionTemplate) (map[string]string, error) {
+	envPairs := tmpl.Env
+	if pc != nil {
+		envPairs = append(append([]string{}, envPairs...), pc.Env...)
+	}
+	// Already validated when the configs were loaded
+	env, _ := config.ParseEnv(envPairs)
+	if err := expandEnv(env); err != nil {
+		return nil, err
+	}
+	return env, nil
+}
+
+// projectEnv returns the session environment a project's layout sets, for
+// sessions recreated without the rest of their layout. Remote projects have
+// none.
+func projectEnv(project finder.Project) (map[string]string, error) {
+	if _, _, ok := config.ParseRemote(project.Path); ok {
+		return nil, nil
+	}
+	pc, tmpl, _, err := projectTemplate(project)
+	if err != nil {
+		return nil, err
+	}
+	return templateEnv(pc, tmpl)
+}
+
+// startupWindow returns the window running a project's startup commands,
+// stopping at the first that fails so its output stays on screen
+func startupWindow(commands []string, projectPath string) Window {
+	return Window{Name: startupWindowName, Command: strings.Join(commands, " && "), Dir: projectPath}
+}
+
+// convertWindows turns configured windows into a layout, resolving working
+// directories against the project path
+func convertWindows(windows []config.Window, projectPath string) (Layout, error) {
+	converted := make([]Window, 0, len(windows))
+	for _, w := range windows {
+		dir := config.WindowDir(w.Dir, projectPath)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return Layout{}, fmt.Errorf("window %s: directory %s does not exist", w.Name, dir)
+		}
+		panes := make([]Pane, 0, len(w.Panes))
+		for _, p := range w.Panes {
+			paneDir := dir
+			if p.Dir != "" {
+				paneDir = config.WindowDir(p.Dir, projectPath)
+			}
+			if info, err := os.Stat(paneDir); err != nil || !info.IsDir() {
+				return Layout{}, fmt.Errorf("window %s: pane directory %s does not exist", w.Name, paneDir)
+			}
+			panes = append(panes, Pane{Command: p.Command, Dir: paneDir, Split: p.Split, Size: p.Size})
+		}
+		// Already validated by config.ResolveWindows
+		env, _ := config.ParseEnv(w.Env)
+		converted = append(converted, Window{Name: w.Name, Command: w.Command, Dir: dir, Env: env, Panes: panes, Layout: w.Layout})
+	}
+	return Layout{Windows: converted}, nil
+}
+
+// CreateSession creates a new tmux session using the project's layout
+func CreateSession(project finder.Project) error {
+	layout, err := layoutFor(project)
+	if err != nil {
+		return err
+	}
+	return CreateSessionWithLayout(project, layout)
+}
+
+// CreateSessionWithLayout creates a new tmux session for project with the given layout
+func CreateSessionWithLayout(project finder.Project, layout Layout) error {
+	windows := layout.Windows
+	if len(windows) == 0 {
+		return fmt.Errorf("session layout has no windows")
+	}
+	sessionName := SessionName(project)
+
+	// Create new session with the first window, capturing its window ID so
+	// later commands target it unambiguously
+	first := windows[0]
+	args := []string{"new-session", "-d", "-s", sessionName, "-c", windowDir(first, project.Path), "-n", first.Name,
+		"-P", "-F", "#{window_id}"}
+	// tmux 3.2+ can set the session environment as the session is created
+	envOnCreate := len(layout.Env) > 0 && VersionAtLeast(3, 2)
+	if envOnCreate {
+		args = append(args, envFlags(layout.Env)...)
+	}
+	cmd := tmuxCmd(args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("failed to create tmux session: %w", err)
+	}
+	firstID := strings.TrimSpace(string(output))
+	slog.Info("created session", "session", sessionName, "path", project.Path, "windows", len(windows))
+
+	// Older tmux: set the session environment afterwards
+	if len(layout.Env) > 0 && !envOnCreate {
+		if err := setSessionEnv(sessionName, layout.Env); err != nil {
+			return err
+		}
+	}
+
+	if err := setSessionOptions(sessionName, layout.Options); err != nil {
+		return err
+	}
+
+	restricted, err := restrictSessionEnv(sessionName, layout.Env)
+	if err != nil {
+		return err
+	}
+
+	// The first window's shell is already running, so restart it if it needs
+	// the session environment set above, variables of its own or to lose
+	// variables the session isn't meant to inherit
+	firstEnv := windowEnv(first)
+	if (len(layout.Env) > 0 && !envOnCreate) || len(firstEnv) > 0 || restricted {
+		args := append([]string{"respawn-pane", "-k", "-t", firstID}, envFlags(firstEnv)...)
+		if err := tmuxCmd(args...).Run(); err != nil {
+			return fmt.Errorf("failed to restart %s window: %w", first.Name, err)
+		}
+	}
+
+	// Record the project path so sesh can recognise its own sessions later
+	cmd = tmuxCmd("set-option", "-t", sessionTarget(sessionName), ProjectOption, project.Path)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to tag session: %w", err)
+	}
+	if profile := config.ActiveProfile(); profile != "" {
+		if err := tmuxCmd("set-option", "-t", sessionTarget(sessionName), ProfileOption, profile).Run(); err != nil {
+			return fmt.Errorf("failed to tag session: %w", err)
+		}
+	}
+
+	if first.Command != "" {
+		if err := sendCommand(firstID, first.Name, first.Command); err != nil {
+			return err
+		}
+	}
+	if err := splitPanes(firstID, first, project.Path); err != nil {
+		return err
+	}
+
+	// Startup commands get the last window, leaving the layout's in place
+	if len(layout.Startup) > 0 {
+		windows = append(windows[:len(windows):len(windows)], startupWindow(layout.Startup, project.Path))
+	}
+	if err := AddWindows(sessionName, project.Path, windows[1:]); err != nil {
+		return err
+	}
+
+	// The first window was numbered before base-index was set
+	if _, ok := layout.Options["base-index"]; ok {
+		if err := tmuxCmd("move-window", "-r", "-t", sessionTarget(sessionName)).Run(); err != nil {
+			return fmt.Errorf("failed to renumber windows: %w", err)
+		}
+	}
+
+	// Select the first window
+	cmd = tmuxCmd("select-window", "-t", firstID)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("failed to select first window: %w", err)
+	}
+
+	for _, w := range windows {
+		if w.Command != "" {
+			watchStartup(sessionName)
+			break
+		}
+	}
+	autoSnapshot("")
+	return nil
+}
+
+// AddWindows appends the given layout windows to an existing session.
+// Names that collide with windows already in the session are suffixed
+// (zsh-2, zsh-3, ...) and every window is addressed by its window ID, so
+// duplicate names can never cause a command to land in the wrong window.
+func AddWindows(sessionName, path string, windows []Window) error {
+	taken, err := windowNames(sessionName)
+	if err != nil {
+		return err
+	}
+
+	for _, w := range windows {
+		name := uniqueWindowName(w.Name, taken)
+		taken[name] = true
+
+		args := []string{"new-window", "-t", sessionTarget(sessionName), "-n", name, "-c", windowDir(w, path), "-P", "-F", "#{window_id}"}
+		args = append(args, envFlags(windowEnv(w))...)
+		cmd := tmuxCmd(args...)
+		output, err := cmd.Output()
+		if err != nil {
+			return fmt.Errorf("failed to create %s window: %w", name, err)
+		}
+		windowID := strings.TrimSpace(string(output))
+
+		if w.Command != "" {
+			if err := sendCommand(windowID, name, w.Command); err != nil {
+				return err
+			}
+		}
+		w.Name = name
+		if err := splitPanes(windowID, w, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitPanes splits a window into the panes of its layout, each off the one
+// before, and arranges them with the window's tmux layout if it names one.
+// The window's own pane stays active.
+func splitPanes(windowID string, w Window, projectPath string) error {
+	if len(w.Panes) == 0 {
+		return nil
+	}
+	output, err := tmuxCmd("display-message", "-p", "-t", windowID, "#{pane_id}").Output()
+	if err != nil {
+		return fmt.Errorf("failed to find %s pane: %w", w.Name, err)
+	}
+	target := strings.TrimSpace(string(output))
+
+	env := envFlags(windowEnv(w))
+	for i, p := range w.Panes {
+		dir := p.Dir
+		if dir == "" {
+			dir = windowDir(w, projectPath)
+		}
+		args := []string{"split-window", "-d", "-t", target, "-c", dir, "-P", "-F", "#{pane_id}"}
+		if p.Split == "horizontal" {
+			args = append(args, "-h")
+		} else {
+			args = append(args, "-v")
+		}
+		args = append(append(args, sizeFlags(p.Size)...), env...)
+		output, err := tmuxCmd(args...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to split %s pane %d: %s", w.Name, i+1, strings.TrimSpace(string(output)))
+		}
+		target = strings.TrimSpace(string(output))
+
+		if p.Command != "" {
+			if err := tmuxCmd("send-keys", "-t", target, p.Command, "Enter").Run(); err != nil {
+				return fmt.Errorf("failed to send %s pane %d command: %w", w.Name, i+1, err)
+			}
+		}
+	}
+
+	if w.Layout != "" {
+		if output, err := tmuxCmd("select-layout", "-t", windowID, w.Layout).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to apply %s layout to %s: %s", w.Layout, w.Name, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// sizeFlags returns the split-window flags for a pane size. tmux before 3.1
+// takes percentages with -p rather than as -l 30%.
+func sizeFlags(size string) []string {
+	if size == "" {
+		return nil
+	}
+	if percent, ok := strings.CutSuffix(size, "%"); ok && !VersionAtLeast(3, 1) {
+		return []string{"-p", percent}
+	}
+	return []string{"-l", size}
+}
+
+// windowDir returns the working directory for a window
+func windowDir(w Window, projectPath string) string {
+	if w.Dir != "" {
+		return w.Dir
+	}
+	return projectPath
+}
+
+// windowNames returns the set of window names currently in a session
+func windowNames(sessionName string) (map[string]bool, error) {
+	cmd := tmuxCmd("list-windows", "-t", sessionTarget(sessionName), "-F", "#{window_name}")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		if line != "" {
+			names[line] = true
+		}
+	}
+	return names, nil
+}
+
+// uniqueWindowName returns name, or name suffixed with -2, -3, ... if it is already taken
+func uniqueWindowName(name string, taken map[string]bool) string {
+	if !taken[name] {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := fmt.Sprintf("%s-%d", name, i)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
+
+// envFlags converts an environment map into sorted -e KEY=VALUE flags
+func envFlags(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	flags := make([]string, 0, len(env)*2)
+	for _, k := range keys {
+		flags = append(flags, "-e", k+"="+env[k])
+	}
+	return flags
+}
+
+// setSessionEnv sets variables in the session environment
+func setSessionEnv(sessionName string, env map[string]string) error {
+	for k, v := range env {
+		cmd := tmuxCmd("set-environment", "-t", sessionTarget(sessionName), k, v)
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("failed to set %s in session environment: %w", k, err)
+		}
+	}
+	return nil
+}
+
+// setSessionOptions sets tmux options on a session, in name order so
+// failures are reported consistently
+func setSessionOptions(sessionName string, options map[string]string) error {
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		output, err := tmuxCmd("set-option", "-t", sessionTarget(sessionName), name, options[name]).CombinedOutput()
+		if msg := strings.TrimSpace(string(output)); err != nil && msg != "" {
+			return fmt.Errorf("failed to set %s option: %s", name, msg)
+		} else if err != nil {
+			return fmt.Errorf("failed to set %s option: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// restrictSessionEnv hides the inherited variables that the session_env
+// config doesn't allow from the session, leaving those in keep, which the
+// layout set on purpose. It reports whether any were hidden.
+func restrictSessionEnv(sessionName string, keep map[string]string) (bool, error) {
+	if cfg == nil || !cfg.SessionEnv.Active() {
+		return false, nil
+	}
+
+	// Panes get the server's global environment plus the variables tmux
+	// copied from the client into the session (update-environment)
+	names := make(map[string]bool)
+	for _, args := range [][]string{{"show-environment", "-g"}, {"show-environment", "-t", sessionTarget(sessionName)}} {
+		output, err := tmuxCmd(args...).Output()
+		if err != nil {
+			return false, fmt.Errorf("failed to read session environment: %w", err)
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			// "-NAME" lines are variables already marked for removal
+			name, _, _ := strings.Cut(line, "=")
+			if name != "" && !strings.HasPrefix(name, "-") {
+				names[name] = true
+			}
+		}
+	}
+
+	hidden := 0
+	for name := range names {
+		if _, ok := keep[name]; ok || cfg.SessionEnv.Allows(name) {
+			continue
+		}
+		if err := tmuxCmd("set-environment", "-t", sessionTarget(sessionName), "-r", name).Run(); err != nil {
+			return false, fmt.Errorf("failed to hide %s from session: %w", name, err)
+		}
+		hidden++
+	}
+	slog.Debug("restricted session environment", "session", sessionName, "hidden", hidden)
+	return hidden > 0, nil
+}
+
+// windowEnv returns the window's environment, or nil with a warning when the
+// installed tmux is too old to set per-window variables (added in 3.0)
+func windowEnv(w Window) map[string]string {
+	if len(w.Env) == 0 {
+		return nil
+	}
+	if !VersionAtLeast(3, 0) {
+		fmt.Fprintf(os.Stderr, "Warning: tmux 3.0 or newer is needed for window env, ignoring env for %s\n", w.Name)
+		return nil
+	}
+	return w.Env
+}
@@ -0,0 +1,81 @@
+package tmux
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/adamflitney/sesh/seshtest"
+)
+
+func TestUniqueWindowName(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		want  string
+	}{
+		{"zsh", nil, "zsh"},
+		{"zsh", []string{"editor"}, "zsh"},
+		{"zsh", []string{"zsh"}, "zsh-2"},
+		{"zsh", []string{"zsh", "zsh-2", "zsh-3"}, "zsh-4"},
+		{"zsh", []string{"zsh", "zsh-3"}, "zsh-2"},
+		{"zsh-2", []string{"zsh-2"}, "zsh-2-2"},
+	}
+	for _, tt := range tests {
+		taken := make(map[string]bool)
+		for _, name := range tt.taken {
+			taken[name] = true
+		}
+		if got := uniqueWindowName(tt.name, taken); got != tt.want {
+			t.Errorf("uniqueWindowName(%q, %v) = %q, want %q", tt.name, tt.taken, got, tt.want)
+		}
+	}
+}
+
+func TestAddWindowsSuffixesTakenNames(t *testing.T) {
+	srv := seshtest.NewServer(t)
+	dir := t.TempDir()
+	srv.Run("new-session", "-d", "-s", "api", "-n", "zsh", "-c", dir)
+	// A session whose name starts with api must not receive the windows
+	srv.Run("new-session", "-d", "-s", "api-old", "-n", "zsh", "-c", dir)
+
+	err := AddWindows("api", dir, []Window{{Name: "zsh"}, {Name: "logs"}, {Name: "zsh"}})
+	if err != nil {
+		t.Fatalf("AddWindows: %v", err)
+	}
+
+	want := []string{"zsh", "zsh-2", "logs", "zsh-3"}
+	if got := srv.Windows("api"); !slices.Equal(got, want) {
+		t.Errorf("api windows = %v, want %v", got, want)
+	}
+	if got := srv.Windows("api-old"); !slices.Equal(got, []string{"zsh"}) {
+		t.Errorf("api-old windows = %v, want [zsh]", got)
+	}
+}
+
+func TestAddWindowsEnv(t *testing.T) {
+	if !VersionAtLeast(3, 0) {
+		t.Skip("window env needs tmux 3.0")
+	}
+	t.Setenv("SHELL", "/bin/sh")
+	srv := seshtest.NewServer(t)
+	dir := t.TempDir()
+	srv.Run("new-session", "-d", "-s", "api", "-n", "zsh", "-c", dir)
+
+	err := AddWindows("api", dir, []Window{
+		{Name: "env", Command: `echo "value:$SESH_TEST_ENV"`, Env: map[string]string{"SESH_TEST_ENV": "from-window"}},
+		{Name: "plain", Command: `echo "value:$SESH_TEST_ENV."`},
+	})
+	if err != nil {
+		t.Fatalf("AddWindows: %v", err)
+	}
+
+	srv.WaitFor(5*time.Second, func() bool {
+		return strings.Contains(srv.Capture("=api:env"), "value:from-window")
+	})
+	// Only the window it is set for sees the variable
+	srv.WaitFor(5*time.Second, func() bool {
+		return strings.Contains(srv.Capture("=api:plain"), "value:.")
+	})
+}
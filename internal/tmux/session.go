@@ -1,19 +1,25 @@
 package tmux
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
 	"github.com/adamflitney/sesh/internal/finder"
 )
 
-// SessionExists checks if a tmux session with the given name exists
+// SessionExists checks if a tmux session with exactly the given name exists
 func SessionExists(name string) (bool, error) {
-	cmd := exec.Command("tmux", "has-session", "-t", name)
+	cmd := tmuxCmd("has-session", "-t", sessionTarget(name))
 	err := cmd.Run()
 	if err != nil {
 		if exitError, ok := err.(*exec.ExitError); ok {
@@ -27,6 +33,13 @@ func SessionExists(name string) (bool, error) {
 	return true, nil
 }
 
+// sessionTarget returns a -t target for exactly the session called name.
+// tmux matches a bare name against the start of session names, so api would
+// find api-gateway when no api session is running.
+func sessionTarget(name string) string {
+	return "=" + name + ":"
+}
+
 // SanitizeSessionName converts a project name to a valid tmux session name
 // Replaces spaces and special characters with hyphens
 func SanitizeSessionName(name string) string {
@@ -43,10 +56,32 @@ func SanitizeSessionName(name string) string {
 	return sanitized
 }
 
+// SessionName returns the tmux session name for project: its fixed name if
+// it has one, otherwise one made from the session_name template
+func SessionName(project finder.Project) string {
+	if project.Session != "" {
+		return SanitizeSessionName(project.Session)
+	}
+	template := ""
+	if cfg != nil {
+		template = cfg.SessionName
+	}
+	if name := SanitizeSessionName(config.ExpandSessionName(template, project.Name, project.Path)); name != "" {
+		return name
+	}
+	return SanitizeSessionName(project.Name)
+}
+
 // tmuxCmd creates an exec.Command for tmux with TMUX env var removed
 // This allows running tmux commands from within a tmux session (e.g., popup)
+// while still talking to the server we're running in, see socketArgs
 func tmuxCmd(args ...string) *exec.Cmd {
-	cmd := exec.Command("tmux", args...)
+	return tmuxCmdContext(context.Background(), args...)
+}
+
+// tmuxCmdContext is tmuxCmd for a command killed once ctx is done
+func tmuxCmdContext(ctx context.Context, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "tmux", append(socketArgs(), args...)...)
 	// Filter out TMUX from environment to allow nested tmux commands
 	env := os.Environ()
 	filteredEnv := make([]string, 0, len(env))
@@ -59,52 +94,73 @@ func tmuxCmd(args ...string) *exec.Cmd {
 	return cmd
 }
 
-// CreateSession creates a new tmux session with three windows
-func CreateSession(project finder.Project) error {
-	sessionName := SanitizeSessionName(project.Name)
+// Command returns a tmux command aimed at the same server sesh manages,
+// with its output connected to ours, for passing user commands through
+func Command(args ...string) *exec.Cmd {
+	cmd := tmuxCmd(args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd
+}
 
-	// Create new session with first window (neovim)
-	cmd := tmuxCmd("new-session", "-d", "-s", sessionName, "-c", project.Path, "-n", "neovim")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create tmux session: %w", err)
-	}
+// clientCmd creates a tmux command that keeps $TMUX, so tmux knows which
+// client sesh was run from, still aimed at the server from socketArgs
+func clientCmd(args ...string) *exec.Cmd {
+	return exec.Command("tmux", append(socketArgs(), args...)...)
+}
 
-	// Send nvim command to first window (use window name instead of index)
-	cmd = tmuxCmd("send-keys", "-t", sessionName+":neovim", "nvim .", "Enter")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to send nvim command: %w", err)
-	}
+// socketName and socketPath pick the tmux server sesh manages, see SetSocket
+var socketName, socketPath string
 
-	// Create second window (opencode)
-	cmd = tmuxCmd("new-window", "-t", sessionName, "-n", "opencode", "-c", project.Path)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create opencode window: %w", err)
-	}
+// SetSocket points sesh at the tmux server listening on the named socket, as
+// tmux -L does, or on the socket at path, as tmux -S does. Either overrides
+// the tmux_socket config.
+func SetSocket(name, path string) {
+	socketName, socketPath = name, path
+}
 
-	// Send opencode command to second window
-	// Start with --port flag so opencode.nvim can connect to it
-	cmd = tmuxCmd("send-keys", "-t", sessionName+":opencode", "opencode --port 0 .", "Enter")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to send opencode command: %w", err)
+// socketArgs returns the flags pointing tmux at the server sesh manages: the
+// one given to SetSocket or by the tmux_socket config, else the one sesh is
+// running inside. $TMUX holds "socket,pid,session"; without it tmux would
+// fall back to the default server, which is wrong for users running several
+// servers.
+func socketArgs() []string {
+	switch {
+	case socketPath != "":
+		return []string{"-S", config.ExpandPath(socketPath)}
+	case socketName != "":
+		return []string{"-L", socketName}
+	case cfg != nil && strings.ContainsRune(cfg.TmuxSocket, '/'):
+		return []string{"-S", config.ExpandPath(cfg.TmuxSocket)}
+	case cfg != nil && cfg.TmuxSocket != "":
+		return []string{"-L", cfg.TmuxSocket}
 	}
-
-	// Create third window (zsh)
-	cmd = tmuxCmd("new-window", "-t", sessionName, "-n", "zsh", "-c", project.Path)
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to create zsh window: %w", err)
+	socket, _, _ := strings.Cut(os.Getenv("TMUX"), ",")
+	if socket == "" {
+		return nil
 	}
+	return []string{"-S", socket}
+}
 
-	// Select the first window (use window name)
-	cmd = tmuxCmd("select-window", "-t", sessionName+":neovim")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("failed to select first window: %w", err)
-	}
+// ProjectOption is the tmux user option sesh sets on sessions it creates,
+// holding the path of the project the session belongs to
+const ProjectOption = "@sesh_project"
 
-	return nil
-}
+// ProfileOption holds the config profile a session was created under, unset
+// without one
+const ProfileOption = "@sesh_profile"
 
 // AttachSession attaches to an existing tmux session
 func AttachSession(sessionName string) error {
+	return attachSession(sessionName, false, false, false)
+}
+
+// attachSession replaces the current process with tmux attached to a session,
+// optionally as a read-only client. With destroyOnDetach the session is
+// removed once its last client detaches; with detachOthers any other clients
+// are detached. Run inside tmux, the new client is nested in the current one.
+func attachSession(sessionName string, readOnly, destroyOnDetach, detachOthers bool) error {
 	// We need to replace the current process with tmux
 	// This is done using syscall.Exec
 	tmuxPath, err := exec.LookPath("tmux")
@@ -112,22 +168,88 @@ func AttachSession(sessionName string) error {
 		return fmt.Errorf("tmux not found in PATH: %w", err)
 	}
 
-	args := []string{"tmux", "attach-session", "-t", sessionName}
-	env := os.Environ()
+	args := append(append([]string{"tmux"}, socketArgs()...), "attach-session", "-t", sessionTarget(sessionName))
+	if readOnly {
+		args = append(args, "-r")
+	}
+	if detachOthers {
+		args = append(args, "-d")
+	}
+	if destroyOnDetach {
+		// Must be set once a client is attached, or tmux destroys the session immediately
+		args = append(args, ";", "set-option", "-t", sessionTarget(sessionName), "destroy-unattached", "on")
+	}
+	// tmux refuses to nest clients while TMUX is set
+	env := make([]string, 0, len(os.Environ()))
+	for _, e := range os.Environ() {
+		if !strings.HasPrefix(e, "TMUX=") {
+			env = append(env, e)
+		}
+	}
 
+	recordSwitch("", sessionName)
 	// Replace current process with tmux
 	return syscall.Exec(tmuxPath, args, env)
 }
 
+// multiClient overrides the multi_client config, see SetMultiClient
+var multiClient string
+
+// SetMultiClient applies a multi_client policy (share, group or mirror) to
+// sessions opened from now on, whatever the config says
+func SetMultiClient(mode string) {
+	multiClient = mode
+}
+
+// targetWindow is the window sessions are opened on, see SetWindow
+var targetWindow string
+
+// SetWindow has sessions opened from now on land on the named window (or
+// window index) rather than the one they were last on. Empty leaves them be.
+func SetWindow(name string) {
+	targetWindow = name
+}
+
+// selectTargetWindow makes the window given to SetWindow the session's
+// current one, so the client switched or attached to it lands there
+func selectTargetWindow(sessionName string) error {
+	if targetWindow == "" {
+		return nil
+	}
+	if err := tmuxCmd("select-window", "-t", "="+sessionName+":"+targetWindow).Run(); err != nil {
+		names, _ := windowNames(sessionName)
+		available := make([]string, 0, len(names))
+		for name := range names {
+			available = append(available, name)
+		}
+		sort.Strings(available)
+		return fmt.Errorf("session %s has no window %s (it has %s)", sessionName, targetWindow, strings.Join(available, ", "))
+	}
+	return nil
+}
+
 // GetOrCreateSession creates a new session if it doesn't exist, or attaches to an existing one
 func GetOrCreateSession(project finder.Project) error {
-	sessionName := SanitizeSessionName(project.Name)
+	layout, err := layoutFor(project)
+	if err != nil {
+		return err
+	}
+	return OpenSession(project, layout)
+}
 
+// OpenSession creates a session with the given layout if it doesn't exist,
+// then switches or attaches to it
+func OpenSession(project finder.Project, layout Layout) error {
 	// Check if tmux is installed
 	if _, err := exec.LookPath("tmux"); err != nil {
 		return fmt.Errorf("tmux is not installed. Please install tmux first")
 	}
 
+	sessionName, err := matchedSession(project)
+	if err != nil {
+		return err
+	}
+
 	// Check if session exists
 	exists, err := SessionExists(sessionName)
 	if err != nil {
@@ -135,44 +257,296 @@ func GetOrCreateSession(project finder.Project) error {
 	}
 
 	if exists {
-		fmt.Printf("Attaching to existing session '%s'...\n", sessionName)
+		if exists, err = resolveConflict(sessionName, project.Path); err != nil {
+			return err
+		}
+	}
+
+	mode := attachMode()
+	readOnly, grouped := false, false
+	if exists {
+		// Another client is already using the session; apply the multi_client
+		// policy unless those clients are about to be detached anyway
+		if mode != "detach-others" && attachedClients(sessionName) > 0 {
+			switch multiClientMode() {
+			case "group":
+				groupName, err := createGroupedSession(sessionName)
+				if err != nil {
+					return err
+				}
+				notify("Session '%s' is in use, created grouped session '%s'...", sessionName, groupName)
+				sessionName, grouped = groupName, true
+			case "mirror":
+				readOnly = true
+			}
+		}
+		notify("Attaching to existing session '%s'...", sessionName)
 	} else {
-		fmt.Printf("Creating new session '%s'...\n", sessionName)
-		if err := CreateSession(project); err != nil {
+		notify("Creating new session '%s'...", sessionName)
+		if layout.CheckoutDefault {
+			checkoutDefaultBranch(project.Path)
+		}
+		// Under the matched name, which may differ from the project's own
+		project.Session = sessionName
+		if err := CreateSessionWithLayout(project, layout); err != nil {
 			return err
 		}
 	}
 
-	// Check if we're inside tmux
-	if os.Getenv("TMUX") != "" {
-		// Switch to session instead of attaching
-		return SwitchSession(sessionName)
+	if err := selectTargetWindow(sessionName); err != nil {
+		return err
+	}
+
+	// Inside tmux, switch to the session instead of attaching unless the
+	// user prefers a nested client
+	if switching() {
+		if mode == "detach-others" {
+			if err := detachOtherClients(sessionName); err != nil {
+				return err
+			}
+		}
+		if err := SwitchSession(sessionName); err != nil {
+			return err
+		}
+		if grouped {
+			return tmuxCmd("set-option", "-t", sessionTarget(sessionName), "destroy-unattached", "on").Run()
+		}
+		return nil
 	}
 
 	// Attach to session (this will replace the current process)
-	return AttachSession(sessionName)
+	return attachSession(sessionName, readOnly, grouped, mode == "detach-others")
+}
+
+// switching reports whether sessions are opened by switching the current
+// client rather than attaching one in this terminal
+func switching() bool {
+	return os.Getenv("TMUX") != "" && attachMode() != "attach"
+}
+
+// notify tells the user what sesh is doing. When switching, the terminal
+// sesh runs in is often a popup or a key binding's shell that closes
+// straight away, so the message goes to the client's status line instead.
+func notify(format string, a ...any) {
+	message := fmt.Sprintf(format, a...)
+	if !switching() {
+		fmt.Println(message)
+		return
+	}
+
+	// Keep TMUX so tmux shows the message on the client sesh was run from.
+	// Doubling # stops tmux from expanding it as a format.
+	args := []string{"display-message"}
+	if client := os.Getenv("SESH_TARGET_CLIENT"); client != "" {
+		args = append(args, "-c", client)
+	}
+	args = append(args, strings.ReplaceAll(message, "#", "##"))
+	if err := clientCmd(args...).Run(); err != nil {
+		fmt.Println(message)
+	}
+}
+
+// attachMode returns the configured attach_mode
+func attachMode() string {
+	if cfg == nil || cfg.AttachMode == "" {
+		return "switch"
+	}
+	return cfg.AttachMode
+}
+
+// detachOtherClients detaches every client attached to a session except
+// the one sesh runs in
+func detachOtherClients(sessionName string) error {
+	self, err := tmuxCmd("display-message", "-p", "#{client_tty}").Output()
+	if err != nil {
+		return fmt.Errorf("failed to find the current client: %w", err)
+	}
+	output, err := tmuxCmd("list-clients", "-t", sessionTarget(sessionName), "-F", "#{client_tty}").Output()
+	if err != nil {
+		return fmt.Errorf("failed to list clients: %w", err)
+	}
+
+	for _, tty := range strings.Fields(string(output)) {
+		if tty == strings.TrimSpace(string(self)) {
+			continue
+		}
+		if err := tmuxCmd("detach-client", "-t", tty).Run(); err != nil {
+			return fmt.Errorf("failed to detach %s: %w", tty, err)
+		}
+	}
+	return nil
+}
+
+// multiClientMode returns the configured multi_client policy
+func multiClientMode() string {
+	if multiClient != "" {
+		return multiClient
+	}
+	if cfg == nil || cfg.MultiClient == "" {
+		return "share"
+	}
+	return cfg.MultiClient
+}
+
+// attachedClients returns the number of clients attached to a session
+func attachedClients(sessionName string) int {
+	cmd := tmuxCmd("display-message", "-p", "-t", sessionTarget(sessionName), "#{session_attached}")
+	output, err := cmd.Output()
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	return n
+}
+
+// createGroupedSession creates a session grouped with target (sharing its
+// windows but with its own current window) and returns its name. It gets a
+// copy of target's environment, as tmux keeps one per session, so windows
+// opened from either see the project's variables.
+func createGroupedSession(target string) (string, error) {
+	name, err := freeSessionName(target)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := tmuxCmd("new-session", "-d", "-t", sessionTarget(target), "-s", name)
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("failed to create grouped session: %w", err)
+	}
+	if err := copySessionEnv(target, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// copySessionEnv copies the environment of session from to session to
+func copySessionEnv(from, to string) error {
+	output, err := tmuxCmd("show-environment", "-t", sessionTarget(from)).Output()
+	if err != nil {
+		return fmt.Errorf("failed to read %s environment: %w", from, err)
+	}
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		args := []string{"set-environment", "-t", sessionTarget(to)}
+		// Variables removed from from are removed from to as well
+		if hidden, ok := strings.CutPrefix(line, "-"); ok {
+			args = append(args, "-r", hidden)
+		} else if name, value, ok := strings.Cut(line, "="); ok {
+			args = append(args, name, value)
+		} else {
+			continue
+		}
+		if err := tmuxCmd(args...).Run(); err != nil {
+			return fmt.Errorf("failed to copy environment to %s: %w", to, err)
+		}
+	}
+	return nil
+}
+
+// freeSessionName returns base, or base suffixed with -2, -3, ... if a
+// session of that name exists
+func freeSessionName(base string) (string, error) {
+	name := base
+	for i := 2; ; i++ {
+		exists, err := SessionExists(name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+}
+
+// RenameSession renames a session and returns its new name, made safe like
+// session names from projects. Jobs started with sesh run in the session stay
+// tracked under the new name, sesh last follows it, and the recent list
+// records it against the session's project, whose name and path (and so its
+// zoxide entry) are unaffected.
+func RenameSession(oldName, newName string) (string, error) {
+	name := SanitizeSessionName(newName)
+	if name == "" {
+		return "", fmt.Errorf("invalid session name: %q", newName)
+	}
+	if name == oldName {
+		return name, nil
+	}
+	exists, err := SessionExists(name)
+	if err != nil {
+		return "", err
+	}
+	if exists {
+		return "", fmt.Errorf("session %s already exists", name)
+	}
+
+	project, _ := tmuxCmd("display-message", "-p", "-t", sessionTarget(oldName), "#{"+ProjectOption+"}").Output()
+	if output, err := tmuxCmd("rename-session", "-t", sessionTarget(oldName), name).CombinedOutput(); err != nil {
+		return "", fmt.Errorf("failed to rename session: %s", strings.TrimSpace(string(output)))
+	}
+
+	if path := strings.TrimSpace(string(project)); path != "" {
+		if recent, err := cache.Load(); err == nil && recent.RenameSession(path, name) {
+			if err := recent.Save(); err != nil {
+				slog.Warn("failed to update recent projects", "error", err)
+			}
+		}
+	}
+
+	recordRename(oldName, name)
+
+	jobs, _ := cache.LoadJobs()
+	for i := range jobs {
+		if jobs[i].Name == oldName {
+			jobs[i].Name = name
+			_ = cache.SaveJobs(jobs)
+			break
+		}
+	}
+	autoSnapshot("")
+	return name, nil
 }
 
 // SwitchSession switches to an existing tmux session (used when already inside tmux)
 func SwitchSession(sessionName string) error {
 	// Check if we have a target client from the environment (set by Raycast script)
-	targetClient := os.Getenv("SESH_TARGET_CLIENT")
+	return SwitchClient(sessionName, os.Getenv("SESH_TARGET_CLIENT"))
+}
 
+// SwitchClient switches the client on the given tty to a session. An empty
+// client means the current one.
+func SwitchClient(sessionName, client string) error {
+	from := clientSession(client)
 	var cmd *exec.Cmd
-	if targetClient != "" {
-		// Target the specific client passed from the popup launcher
-		cmd = exec.Command("tmux", "switch-client", "-t", sessionName, "-c", targetClient)
+	if client != "" {
+		// Target the specific client, e.g. one passed from a popup launcher
+		cmd = clientCmd("switch-client", "-t", sessionTarget(sessionName), "-c", client)
 	} else {
 		// Default: switch current client
-		cmd = exec.Command("tmux", "switch-client", "-t", sessionName)
+		cmd = clientCmd("switch-client", "-t", sessionTarget(sessionName))
 	}
 
-	return cmd.Run()
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	recordSwitch(from, sessionName)
+	return nil
+}
+
+// ClientExists reports whether a tmux client is attached on the given tty
+func ClientExists(tty string) bool {
+	for _, ttys := range listClients(context.Background()) {
+		for _, t := range ttys {
+			if t == tty {
+				return true
+			}
+		}
+	}
+	return false
 }
 
 // ListSessions returns a list of active tmux session names
 func ListSessions() ([]string, error) {
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}")
+	cmd := tmuxCmd("list-sessions", "-F", "#{session_name}")
 	output, err := cmd.Output()
 	if err != nil {
 		return nil, err
@@ -187,3 +561,83 @@ func ListSessions() ([]string, error) {
 	}
 	return sessions, nil
 }
+
+// fieldSep separates fields in tmux format strings; tmux replaces control
+// characters such as tabs in its output, so a printable separator is used
+const fieldSep = "|"
+
+// SessionInfo describes an active tmux session
+type SessionInfo struct {
+	Name     string
+	Path     string
+	Project  string // Project path recorded by sesh, empty for sessions sesh didn't create
+	Profile  string // Config profile the session was created under, see ProfileOption
+	Attached int
+	Clients  []string // TTYs of clients attached to the session
+}
+
+// ListSessionInfo returns details about every active tmux session, killing
+// tmux commands still running when ctx is done
+func ListSessionInfo(ctx context.Context) ([]SessionInfo, error) {
+	format := strings.Join([]string{"#{session_name}", "#{session_attached}", "#{" + ProjectOption + "}", "#{" + ProfileOption + "}",
+		"#{session_path}"}, fieldSep)
+	cmd := tmuxCmdContext(ctx, "list-sessions", "-F", format)
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	clients := listClients(ctx)
+
+	var sessions []SessionInfo
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, fieldSep, 5)
+		if len(parts) != 5 {
+			continue
+		}
+		attached, _ := strconv.Atoi(parts[1])
+		sessions = append(sessions, SessionInfo{
+			Name:     parts[0],
+			Path:     parts[4],
+			Project:  parts[2],
+			Profile:  parts[3],
+			Attached: attached,
+			Clients:  clients[parts[0]],
+		})
+	}
+	return sessions, nil
+}
+
+// listClients maps session names to the TTYs of their attached clients
+func listClients(ctx context.Context) map[string][]string {
+	clients := make(map[string][]string)
+
+	cmd := tmuxCmdContext(ctx, "list-clients", "-F", "#{session_name}"+fieldSep+"#{client_tty}")
+	output, err := cmd.Output()
+	if err != nil {
+		return clients
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, fieldSep, 2)
+		if len(parts) == 2 {
+			clients[parts[0]] = append(clients[parts[0]], parts[1])
+		}
+	}
+	return clients
+}
+
+// CurrentSession returns the name of the session sesh is running in, or an
+// empty string when not inside tmux
+func CurrentSession() string {
+	if os.Getenv("TMUX") == "" {
+		return ""
+	}
+
+	cmd := clientCmd("display-message", "-p", "#{session_name}")
+	output, err := cmd.Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
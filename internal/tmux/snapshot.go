@@ -0,0 +1,312 @@
+package tmux
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/finder"
+)
+
+// snapshotOption is the server option set once the snapshot file describes
+// this server's sessions, by saving or restoring it. Until then automatic
+// snapshots leave the file alone, as it still holds the sessions of a
+// server that has gone and is waiting to be restored.
+const snapshotOption = "@sesh_snapshot"
+
+// TakeSnapshot records the sessions sesh created on the tmux server, with
+// their windows, layouts and the directories of their panes
+func TakeSnapshot() (*cache.Snapshot, error) {
+	return takeSnapshot("")
+}
+
+// takeSnapshot is TakeSnapshot leaving out the session named except
+func takeSnapshot(except string) (*cache.Snapshot, error) {
+	sessions, err := ListSessionInfo(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("no tmux sessions to snapshot")
+	}
+
+	snap := &cache.Snapshot{SavedAt: time.Now(), Sessions: []cache.SnapshotSession{}}
+	for _, s := range sessions {
+		// Sessions sesh didn't create have no project to come back to
+		if s.Project == "" || s.Name == except {
+			continue
+		}
+		windows, err := snapshotWindows(s.Name)
+		if err != nil {
+			return nil, err
+		}
+		snap.Sessions = append(snap.Sessions, cache.SnapshotSession{
+			Name:    s.Name,
+			Project: s.Project,
+			Profile: s.Profile,
+			Windows: windows,
+		})
+	}
+	return snap, nil
+}
+
+// SaveSnapshot takes a snapshot and writes it to path. Saved to the default
+// path, it is also where automatic snapshots continue from.
+func SaveSnapshot(path string) (*cache.Snapshot, error) {
+	snap, err := TakeSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	if len(snap.Sessions) == 0 {
+		return nil, fmt.Errorf("no sesh sessions to snapshot")
+	}
+	if err := cache.SaveSnapshot(path, *snap); err != nil {
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+	if defaultPath, err := cache.SnapshotPath(); err == nil && path == defaultPath {
+		markSnapshotCurrent()
+	}
+	return snap, nil
+}
+
+// autoSnapshot updates the default snapshot with auto_snapshot on, leaving
+// out the session named except, which is about to be killed. Failures are
+// only logged, as they shouldn't fail what changed the sessions.
+func autoSnapshot(except string) {
+	if cfg == nil || !cfg.AutoSnapshot {
+		return
+	}
+	path, err := cache.SnapshotPath()
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(path); err == nil && !snapshotCurrent() {
+		slog.Debug("snapshot not restored since the tmux server started, leaving it")
+		return
+	}
+
+	snap, err := takeSnapshot(except)
+	if err == nil {
+		err = cache.SaveSnapshot(path, *snap)
+	}
+	if err != nil {
+		slog.Warn("failed to update snapshot", "error", err)
+		return
+	}
+	markSnapshotCurrent()
+}
+
+// AutoSnapshot updates the default snapshot when auto_snapshot is on, for
+// tmux hooks to call as windows and panes change
+func AutoSnapshot() {
+	autoSnapshot("")
+}
+
+// snapshotCurrent reports whether the snapshot file was saved or restored
+// by the running tmux server
+func snapshotCurrent() bool {
+	output, err := tmuxCmd("show-options", "-gqv", snapshotOption).Output()
+	return err == nil && strings.TrimSpace(string(output)) == "1"
+}
+
+// markSnapshotCurrent records on the server that the snapshot file now
+// describes its sessions
+func markSnapshotCurrent() {
+	if err := tmuxCmd("set-option", "-g", snapshotOption, "1").Run(); err != nil {
+		slog.Debug("failed to mark snapshot", "error", err)
+	}
+}
+
+// snapshotWindows records the windows of a session in order
+func snapshotWindows(sessionName string) ([]cache.SnapshotWindow, error) {
+	format := strings.Join([]string{"#{window_id}", "#{window_active}", "#{window_layout}", "#{pane_current_path}", "#{window_name}"}, fieldSep)
+	output, err := tmuxCmd("list-panes", "-s", "-t", sessionTarget(sessionName), "-F", format).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list panes of %s: %w", sessionName, err)
+	}
+
+	var windows []cache.SnapshotWindow
+	lastID := ""
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		// The name comes last as it may contain the separator
+		parts := strings.SplitN(line, fieldSep, 5)
+		if len(parts) != 5 {
+			continue
+		}
+		if parts[0] != lastID {
+			lastID = parts[0]
+			windows = append(windows, cache.SnapshotWindow{Name: parts[4], Layout: parts[2], Active: parts[1] == "1"})
+		}
+		w := &windows[len(windows)-1]
+		w.Panes = append(w.Panes, cache.SnapshotPane{Path: parts[3]})
+	}
+	return windows, nil
+}
+
+// RestoreSnapshot recreates the sessions in a snapshot that aren't running,
+// returning the names of those it created. Panes start as plain shells in
+// their recorded directories, or the project's if those are gone.
+func RestoreSnapshot(snap *cache.Snapshot) ([]string, error) {
+	var restored []string
+	var errs []error
+	for _, s := range snap.Sessions {
+		if len(s.Windows) == 0 {
+			continue
+		}
+		if exists, _ := SessionExists(s.Name); exists {
+			slog.Debug("session already running, not restoring it", "session", s.Name)
+			continue
+		}
+		if err := restoreSession(s); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.Name, err))
+			continue
+		}
+		restored = append(restored, s.Name)
+	}
+	return restored, errors.Join(errs...)
+}
+
+// restoreSession creates one session of a snapshot, detached, with its
+// project's environment set before any of its shells start
+func restoreSession(s cache.SnapshotSession) error {
+	env, err := projectEnv(finder.Project{Name: filepath.Base(s.Project), Path: s.Project})
+	if err != nil {
+		slog.Warn("restoring session without its environment", "session", s.Name, "error", err)
+		env = nil
+	}
+
+	fallback := existingDir(s.Project, "")
+	first := s.Windows[0]
+	args := []string{"new-session", "-d", "-s", s.Name, "-n", first.Name,
+		"-c", existingDir(panePath(first, 0), fallback), "-P", "-F", "#{window_id}"}
+	envOnCreate := len(env) > 0 && VersionAtLeast(3, 2)
+	if envOnCreate {
+		args = append(args, envFlags(env)...)
+	}
+	output, err := tmuxCmd(args...).Output()
+	if err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	windowIDs := []string{strings.TrimSpace(string(output))}
+
+	// Older tmux: set it afterwards
+	if len(env) > 0 && !envOnCreate {
+		if err := setSessionEnv(s.Name, env); err != nil {
+			return err
+		}
+	}
+	restricted, err := restrictSessionEnv(s.Name, env)
+	if err != nil {
+		return err
+	}
+	// Restart the first window's shell if it missed the environment set
+	// above or has variables session_env doesn't allow
+	if (len(env) > 0 && !envOnCreate) || restricted {
+		if err := tmuxCmd("respawn-pane", "-k", "-t", windowIDs[0]).Run(); err != nil {
+			return fmt.Errorf("failed to restart %s window: %w", first.Name, err)
+		}
+	}
+
+	if err := tmuxCmd("set-option", "-t", sessionTarget(s.Name), ProjectOption, s.Project).Run(); err != nil {
+		return fmt.Errorf("failed to tag session: %w", err)
+	}
+	if s.Profile != "" {
+		if err := tmuxCmd("set-option", "-t", sessionTarget(s.Name), ProfileOption, s.Profile).Run(); err != nil {
+			return fmt.Errorf("failed to tag session: %w", err)
+		}
+	}
+
+	for _, w := range s.Windows[1:] {
+		output, err := tmuxCmd("new-window", "-d", "-t", sessionTarget(s.Name), "-n", w.Name,
+			"-c", existingDir(panePath(w, 0), fallback), "-P", "-F", "#{window_id}").Output()
+		if err != nil {
+			return fmt.Errorf("failed to create %s window: %w", w.Name, err)
+		}
+		windowIDs = append(windowIDs, strings.TrimSpace(string(output)))
+	}
+
+	for i, w := range s.Windows {
+		if err := restorePanes(windowIDs[i], w, fallback); err != nil {
+			return err
+		}
+		if w.Active {
+			_ = tmuxCmd("select-window", "-t", windowIDs[i]).Run()
+		}
+	}
+	slog.Info("restored session", "session", s.Name, "windows", len(s.Windows))
+	return nil
+}
+
+// restorePanes splits a restored window into its recorded panes and applies
+// its recorded layout
+func restorePanes(windowID string, w cache.SnapshotWindow, fallback string) error {
+	for i := 1; i < len(w.Panes); i++ {
+		output, err := tmuxCmd("split-window", "-d", "-t", windowID, "-c", existingDir(panePath(w, i), fallback)).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("failed to split %s window: %s", w.Name, strings.TrimSpace(string(output)))
+		}
+		// Spread the panes out so the next split has room, whatever the
+		// size of the detached session
+		_ = tmuxCmd("select-layout", "-t", windowID, "tiled").Run()
+	}
+
+	if len(w.Panes) > 1 && w.Layout != "" {
+		// A layout recorded for a bigger terminal may not fit; the tiled one stays
+		if output, err := tmuxCmd("select-layout", "-t", windowID, w.Layout).CombinedOutput(); err != nil {
+			slog.Warn("failed to restore window layout", "window", w.Name, "error", strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// panePath returns the recorded directory of a window's pane, empty if it
+// has none
+func panePath(w cache.SnapshotWindow, i int) string {
+	if i < len(w.Panes) {
+		return w.Panes[i].Path
+	}
+	return ""
+}
+
+// existingDir returns dir if it is still a directory, otherwise fallback,
+// otherwise the home directory
+func existingDir(dir, fallback string) string {
+	if info, err := os.Stat(dir); dir != "" && err == nil && info.IsDir() {
+		return dir
+	}
+	if fallback != "" {
+		return fallback
+	}
+	home, _ := os.UserHomeDir()
+	return home
+}
+
+// AutoRestore recreates the sessions of the default snapshot with
+// auto_snapshot on, once per tmux server: it does nothing when the server
+// has already saved or restored the snapshot. It returns the names of the
+// sessions it created.
+func AutoRestore() ([]string, error) {
+	if cfg == nil || !cfg.AutoSnapshot || snapshotCurrent() {
+		return nil, nil
+	}
+	path, err := cache.SnapshotPath()
+	if err != nil {
+		return nil, err
+	}
+	snap, err := cache.LoadSnapshot(path)
+	if os.IsNotExist(err) {
+		markSnapshotCurrent()
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	restored, err := RestoreSnapshot(snap)
+	markSnapshotCurrent()
+	autoSnapshot("")
+	return restored, err
+}
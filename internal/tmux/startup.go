@@ -0,0 +1,140 @@
+package tmux
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+)
+
+// CommandOption is the tmux window option holding the startup command sesh
+// sent to a window
+const CommandOption = "@sesh_command"
+
+// startupGrace is how long startup commands get before CheckStartup looks
+// at them, in seconds
+const startupGrace = 2
+
+// shells are the programs a pane runs once its startup command has exited
+var shells = []string{"sh", "bash", "zsh", "fish", "dash", "ksh", "tcsh", "nu"}
+
+// startupError matches what shells print when a command can't be run, such
+// as "zsh: command not found: nvimm" or "bash: syntax error near ..."
+var startupError = regexp.MustCompile(`(?i)command not found|: not found|unknown command|no such file or directory|permission denied|syntax error|parse error`)
+
+// StartupFailure is a window whose startup command exited with an error
+type StartupFailure struct {
+	Window  string
+	Command string
+	Error   string // The shell's error message
+}
+
+// sendCommand types a window's startup command into its shell and records
+// it on the window for CheckStartup
+func sendCommand(windowID, name, command string) error {
+	if err := tmuxCmd("send-keys", "-t", windowID, command, "Enter").Run(); err != nil {
+		return fmt.Errorf("failed to send %s command: %w", name, err)
+	}
+	if err := tmuxCmd("set-option", "-w", "-t", windowID, CommandOption, command).Run(); err != nil {
+		return fmt.Errorf("failed to tag %s window: %w", name, err)
+	}
+	return nil
+}
+
+// watchStartup has the tmux server run sesh check-startup on a session once
+// its startup commands have had time to fail. The server runs it so it
+// survives sesh replacing itself with an attached client.
+func watchStartup(sessionName string) {
+	exe, err := os.Executable()
+	if err != nil {
+		return
+	}
+	command := fmt.Sprintf("sleep %d; %s", startupGrace, shellQuote(exe))
+	// The server's environment needn't have the SESH_CONFIG or SESH_PROFILE
+	// this sesh has, so the config and profile in use are passed on
+	if file := config.ExplicitConfigFile(); file != "" {
+		if abs, err := filepath.Abs(file); err == nil {
+			file = abs
+		}
+		command += " --config " + shellQuote(file)
+	}
+	if profile := config.ActiveProfile(); profile != "" {
+		command += " --profile " + shellQuote(profile)
+	}
+	command += " check-startup " + shellQuote(sessionName)
+	// run-shell expands formats, so # is doubled
+	if err := tmuxCmd("run-shell", "-b", strings.ReplaceAll(command, "#", "##")).Run(); err != nil {
+		slog.Debug("failed to watch startup commands", "session", sessionName, "error", err)
+	}
+}
+
+// StartupFailures returns the windows of a session whose startup command has
+// exited, leaving the shell with an error on screen
+func StartupFailures(sessionName string) ([]StartupFailure, error) {
+	format := strings.Join([]string{"#{window_id}", "#{window_name}", "#{pane_current_command}", "#{" + CommandOption + "}"}, fieldSep)
+	output, err := tmuxCmd("list-windows", "-t", sessionTarget(sessionName), "-F", format).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list windows of %s: %w", sessionName, err)
+	}
+
+	var failures []StartupFailure
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		parts := strings.SplitN(line, fieldSep, 4)
+		if len(parts) != 4 || parts[3] == "" || !isShell(parts[2]) {
+			continue
+		}
+		screen, err := tmuxCmd("capture-pane", "-p", "-t", parts[0]).Output()
+		if err != nil {
+			continue
+		}
+		for _, text := range strings.Split(string(screen), "\n") {
+			if startupError.MatchString(text) {
+				failures = append(failures, StartupFailure{Window: parts[1], Command: parts[3], Error: strings.TrimSpace(text)})
+				break
+			}
+		}
+	}
+	return failures, nil
+}
+
+// ReportStartupFailures shows a message on the session's clients naming
+// the windows whose startup command failed
+func ReportStartupFailures(sessionName string, failures []StartupFailure) error {
+	names := make([]string, len(failures))
+	for i, f := range failures {
+		names[i] = f.Window
+		slog.Warn("startup command failed", "session", sessionName, "window", f.Window, "command", f.Command, "error", f.Error)
+	}
+	message := fmt.Sprintf("sesh: startup command failed in %s: %s", strings.Join(names, ", "), failures[0].Error)
+
+	args := []string{"display-message", "-t", sessionTarget(sessionName)}
+	if VersionAtLeast(3, 2) {
+		// Long enough to read, however short display-time is
+		args = append(args, "-d", "5000")
+	}
+	return tmuxCmd(append(args, strings.ReplaceAll(message, "#", "##"))...).Run()
+}
+
+// isShell reports whether a pane's current command is a shell, meaning
+// whatever was started in it has exited
+func isShell(command string) bool {
+	command = strings.TrimPrefix(command, "-") // Login shells
+	if command == filepath.Base(os.Getenv("SHELL")) {
+		return true
+	}
+	for _, shell := range shells {
+		if command == shell {
+			return true
+		}
+	}
+	return false
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
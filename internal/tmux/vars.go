@@ -0,0 +1,110 @@
+package tmux
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+)
+
+// varPattern matches a layout variable, {{name}} or {{name:default}}.
+// Anything else in braces, such as tmux or Go template syntax, is left alone.
+var varPattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)(?::([^}]*))?\s*\}\}`)
+
+// vars holds the values of layout variables, see SetVars
+var vars = map[string]string{}
+
+// promptVar asks for the value of a variable that was not given, nil when
+// sesh can't ask
+var promptVar func(name, def string) (string, error)
+
+// SetVars sets values for the {{name}} variables in layouts, e.g. from
+// sesh connect --var
+func SetVars(values map[string]string) {
+	for k, v := range values {
+		vars[k] = v
+	}
+}
+
+// SetVarPrompt sets how to ask for variables that weren't given. Without
+// one, a variable with no value or default is an error.
+func SetVarPrompt(prompt func(name, def string) (string, error)) {
+	promptVar = prompt
+}
+
+// expandVars replaces the variables in s with their values
+func expandVars(s string) (string, error) {
+	var err error
+	expanded := varPattern.ReplaceAllStringFunc(s, func(match string) string {
+		m := varPattern.FindStringSubmatch(match)
+		value, verr := varValue(m[1], m[2], strings.Contains(match, ":"))
+		if verr != nil && err == nil {
+			err = verr
+		}
+		return value
+	})
+	return expanded, err
+}
+
+// varValue returns a variable's value: the one given, else the one asked
+// for, else its default. Answers are remembered so each variable is only
+// asked for once.
+func varValue(name, def string, hasDefault bool) (string, error) {
+	if value, ok := vars[name]; ok {
+		return value, nil
+	}
+	if promptVar != nil {
+		value, err := promptVar(name, def)
+		if err != nil {
+			return "", err
+		}
+		if value == "" && hasDefault {
+			value = def
+		}
+		vars[name] = value
+		return value, nil
+	}
+	if hasDefault {
+		return def, nil
+	}
+	return "", fmt.Errorf("the layout needs a value for {{%s}}, pass --var %s=<value>", name, name)
+}
+
+// expandWindows replaces the variables in window names, commands, working
+// directories and environment variables
+func expandWindows(windows []config.Window) error {
+	for i := range windows {
+		w := &windows[i]
+		fields := []*string{&w.Name, &w.Command, &w.Dir}
+		w.Env = append([]string{}, w.Env...)
+		for j := range w.Env {
+			fields = append(fields, &w.Env[j])
+		}
+		w.Panes = append([]config.Pane{}, w.Panes...)
+		for j := range w.Panes {
+			fields = append(fields, &w.Panes[j].Command, &w.Panes[j].Dir)
+		}
+
+		for _, field := range fields {
+			value, err := expandVars(*field)
+			if err != nil {
+				return fmt.Errorf("window %s: %w", w.Name, err)
+			}
+			*field = value
+		}
+	}
+	return nil
+}
+
+// expandEnv replaces the variables in session environment values in place
+func expandEnv(env map[string]string) error {
+	for k, v := range env {
+		value, err := expandVars(v)
+		if err != nil {
+			return fmt.Errorf("env %s: %w", k, err)
+		}
+		env[k] = value
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package tmux
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	versionOnce  sync.Once
+	versionMajor int
+	versionMinor int
+)
+
+// VersionAtLeast reports whether the installed tmux is at least major.minor.
+// Unparseable versions (e.g. builds from master) are assumed to be recent.
+func VersionAtLeast(major, minor int) bool {
+	versionOnce.Do(detectVersion)
+	if versionMajor != major {
+		return versionMajor > major
+	}
+	return versionMinor >= minor
+}
+
+// detectVersion parses the output of tmux -V, e.g. "tmux 3.3a" or "tmux next-3.4"
+func detectVersion() {
+	// Assume a modern tmux unless we learn otherwise
+	versionMajor, versionMinor = 99, 0
+
+	output, err := exec.Command("tmux", "-V").Output()
+	if err != nil {
+		return
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return
+	}
+	version := fields[1]
+	if idx := strings.LastIndex(version, "-"); idx >= 0 {
+		version = version[idx+1:]
+	}
+
+	parts := strings.SplitN(version, ".", 2)
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return
+	}
+	minor := 0
+	if len(parts) == 2 {
+		digits := strings.TrimRightFunc(parts[1], func(r rune) bool { return r < '0' || r > '9' })
+		minor, _ = strconv.Atoi(digits)
+	}
+	versionMajor, versionMinor = major, minor
+}
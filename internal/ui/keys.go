@@ -0,0 +1,49 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+)
+
+// keys are the keys bound to each picker action, see SetKeys
+var keys = config.DefaultKeys
+
+// keyActions maps each bound key to its action
+var keyActions = actionsFor(keys)
+
+// keySymbols are shorter names for keys in the help
+var keySymbols = map[string]string{"up": "↑", "down": "↓"}
+
+// SetKeys binds the picker's actions to keys, as resolved by the config
+func SetKeys(bindings map[string][]string) {
+	keys = bindings
+	keyActions = actionsFor(bindings)
+}
+
+// actionsFor inverts action bindings into a key lookup
+func actionsFor(bindings map[string][]string) map[string]string {
+	actions := make(map[string]string)
+	for action, ks := range bindings {
+		for _, k := range ks {
+			actions[k] = action
+		}
+	}
+	return actions
+}
+
+// keyHelp describes an action's keys for the help line, e.g. "↑/k up", or
+// returns an empty string when it has none
+func keyHelp(action, label string) string {
+	if len(keys[action]) == 0 {
+		return ""
+	}
+	names := make([]string, len(keys[action]))
+	for i, k := range keys[action] {
+		if symbol, ok := keySymbols[k]; ok {
+			k = symbol
+		}
+		names[i] = k
+	}
+	return strings.Join(names, "/") + " " + label
+}
@@ -1,42 +1,98 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"slices"
 	"strings"
+	"time"
 
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
 	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/preview"
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 	"github.com/sahilm/fuzzy"
 )
 
-var (
-	titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(lipgloss.Color("#7D56F4")).
-			MarginBottom(1)
+const (
+	// defaultHeight is assumed until the terminal reports its size
+	defaultHeight = 24
+	// minListLines is the least room for the list before the title, preview
+	// and help are dropped
+	minListLines = 6
+)
+
+// Options customises the picker
+type Options struct {
+	// Kill enables marking items (tab by default) and killing them (ctrl+x),
+	// the highlighted item if none are marked. It is called for each item once
+	// the user confirms.
+	Kill func(finder.Project) error
+
+	// Reload enables the reload key (ctrl+r), which replaces the list with the one it
+	// returns, e.g. after reloading the config and rescanning. ctx is cancelled
+	// when the picker exits, so a reload still running then can stop.
+	Reload func(ctx context.Context) ([]finder.Project, *config.Config, error)
+
+	// Configure applies the config returned by Reload. Reload runs in the
+	// background, so it leaves settings the picker reads, such as its keys
+	// and theme, for Configure to change between updates.
+	Configure func(*config.Config) error
+
+	// Rename enables the rename key (ctrl+e), which edits the highlighted item's name in
+	// place. It is called with the name typed and returns the name the item
+	// ended up with.
+	Rename func(item finder.Project, name string) (string, error)
+
+	// Sort is the order the projects come in, one of config.SortOrders.
+	// Setting it enables the sort key (ctrl+s) to cycle through the orders.
+	Sort string
+}
+
+// projectsMsg replaces the project list, e.g. after a rescan
+type projectsMsg struct {
+	projects []finder.Project
+}
 
-	selectedStyle = lipgloss.NewStyle().
-			Background(lipgloss.Color("#7D56F4")).
-			Foreground(lipgloss.Color("#FFFFFF")).
-			Bold(true)
+// killedMsg reports which items were killed and any failures
+type killedMsg struct {
+	killed []finder.Project
+	err    error
+}
 
-	normalStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FFFFFF"))
+// reloadedMsg delivers the result of Options.Reload
+type reloadedMsg struct {
+	projects []finder.Project
+	cfg      *config.Config
+	err      error
+}
 
-	pathStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#888888")).
-			Italic(true)
+// renamedMsg delivers the result of Options.Rename
+type renamedMsg struct {
+	old  string
+	name string
+	err  error
+}
 
-	helpStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#626262")).
-			MarginTop(1)
+// projectPreview is the data shown in the preview pane for a project
+type projectPreview struct {
+	health  preview.Health
+	summary preview.Summary
+	size    *cache.RepoSize // nil when the size couldn't be measured
+	history cache.ProjectHistory
+}
 
-	errorStyle = lipgloss.NewStyle().
-			Foreground(lipgloss.Color("#FF0000")).
-			Bold(true)
-)
+// previewMsg delivers lazily computed preview data for a project path
+type previewMsg struct {
+	path    string
+	preview projectPreview
+}
 
 type model struct {
 	projects  []finder.Project
@@ -46,10 +102,20 @@ type model struct {
 	selected  *finder.Project
 	quitting  bool
 	err       error
+	width     int
 	height    int
+	previews  map[string]*projectPreview // Preview data by project path, nil while loading
+	opts      Options
+	marked    map[string]bool  // Names of items marked with tab
+	confirm   []finder.Project // Items awaiting kill confirmation
+	status    string           // Outcome of the last action, shown above the help
+	sort      string           // Current order of projects, see Options.Sort
+	renaming  bool             // Whether the highlighted item's name is being edited
+	rename    textinput.Model  // The new name while renaming
+	ctx       context.Context  // Done once the picker exits
 }
 
-func initialModel(projects []finder.Project) model {
+func initialModel(ctx context.Context, projects []finder.Project, opts Options) model {
 	ti := textinput.New()
 	ti.Placeholder = "Search projects..."
 	ti.Focus()
@@ -61,11 +127,45 @@ func initialModel(projects []finder.Project) model {
 		filtered:  projects,
 		cursor:    0,
 		textInput: ti,
+		previews:  make(map[string]*projectPreview),
+		opts:      opts,
+		marked:    make(map[string]bool),
+		sort:      opts.Sort,
+		ctx:       ctx,
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, m.previewCmd())
+}
+
+// previewCmd starts loading preview data for the highlighted project unless
+// it is already cached or in flight
+func (m model) previewCmd() tea.Cmd {
+	if m.cursor >= len(m.filtered) {
+		return nil
+	}
+	path := m.filtered[m.cursor].Path
+	if _, ok := m.previews[path]; ok {
+		return nil
+	}
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil
+	}
+
+	m.previews[path] = nil
+	return func() tea.Msg {
+		p := projectPreview{
+			health:  preview.LoadHealth(path),
+			summary: preview.LoadSummary(path),
+		}
+		if size, err := preview.LoadSize(path); err == nil {
+			p.size = &size
+		}
+		history, _ := cache.LoadHistory()
+		p.history = history[path]
+		return previewMsg{path: path, preview: p}
+	}
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -73,31 +173,148 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
+		m.width = msg.Width
 		m.height = msg.Height
+		// Keep the search box within narrow terminals
+		m.textInput.Width = min(50, max(10, msg.Width-4))
 		return m, nil
 
+	case previewMsg:
+		p := msg.preview
+		m.previews[msg.path] = &p
+		return m, nil
+
+	case projectsMsg:
+		m.setProjects(msg.projects)
+		return m, m.previewCmd()
+
+	case killedMsg:
+		return m.handleKilled(msg)
+
+	case reloadedMsg:
+		if msg.err == nil && msg.cfg != nil && m.opts.Configure != nil {
+			msg.err = m.opts.Configure(msg.cfg)
+		}
+		if msg.err != nil {
+			m.status = errorStyle.Render(msg.err.Error())
+			return m, nil
+		}
+		m.status = fmt.Sprintf("Reloaded, %s", plural(len(msg.projects), "project"))
+		// Previews may depend on what changed, so load them again
+		m.previews = make(map[string]*projectPreview)
+		if m.sort != "" {
+			// Keep the order picked with ctrl+s
+			finder.Sort(msg.projects, m.sort)
+		}
+		m.setProjects(msg.projects)
+		return m, m.previewCmd()
+
+	case renamedMsg:
+		return m.handleRenamed(msg)
+
 	case tea.KeyMsg:
-		switch msg.String() {
-		case "ctrl+c", "esc":
+		if m.confirm != nil {
+			return m.handleConfirm(msg)
+		}
+		if m.renaming {
+			return m.handleRename(msg)
+		}
+		m.status = ""
+
+		if msg.String() == "ctrl+c" {
+			m.quitting = true
+			return m, tea.Quit
+		}
+
+		switch keyActions[msg.String()] {
+		case "quit":
 			m.quitting = true
 			return m, tea.Quit
 
-		case "enter":
+		case "select":
 			if len(m.filtered) > 0 && m.cursor < len(m.filtered) {
 				m.selected = &m.filtered[m.cursor]
 				m.quitting = true
 				return m, tea.Quit
 			}
 
-		case "up", "k":
+		case "up":
 			if m.cursor > 0 {
 				m.cursor--
 			}
+			return m, m.previewCmd()
+
+		case "down":
+			if m.cursor < len(m.filtered)-1 {
+				m.cursor++
+			}
+			return m, m.previewCmd()
 
-		case "down", "j":
+		case "mark":
+			if m.opts.Kill == nil || len(m.filtered) == 0 {
+				return m, nil
+			}
+			name := m.filtered[m.cursor].Name
+			if m.marked[name] {
+				delete(m.marked, name)
+			} else {
+				m.marked[name] = true
+			}
+			// Move on so consecutive items can be marked with repeated tabs
 			if m.cursor < len(m.filtered)-1 {
 				m.cursor++
 			}
+			return m, m.previewCmd()
+
+		case "reload":
+			if m.opts.Reload == nil {
+				return m, nil
+			}
+			m.status = "Reloading..."
+			reload, ctx := m.opts.Reload, m.ctx
+			return m, func() tea.Msg {
+				projects, cfg, err := reload(ctx)
+				return reloadedMsg{projects: projects, cfg: cfg, err: err}
+			}
+
+		case "toggle-sort":
+			if m.sort == "" {
+				return m, nil
+			}
+			i := slices.Index(config.SortOrders, m.sort)
+			m.sort = config.SortOrders[(i+1)%len(config.SortOrders)]
+			sorted := slices.Clone(m.projects)
+			finder.Sort(sorted, m.sort)
+			m.setProjects(sorted)
+			m.cursor = 0
+			m.status = "Sorted by " + m.sort
+			return m, m.previewCmd()
+
+		case "rename-session":
+			if m.opts.Rename == nil || len(m.filtered) == 0 {
+				return m, nil
+			}
+			m.renaming = true
+			m.rename = textinput.New()
+			m.rename.Prompt = ""
+			m.rename.CharLimit = m.textInput.CharLimit
+			m.rename.SetValue(m.filtered[m.cursor].Name)
+			m.textInput.Blur()
+			return m, m.rename.Focus()
+
+		case "kill-session":
+			if m.opts.Kill == nil || len(m.filtered) == 0 {
+				return m, nil
+			}
+			for _, p := range m.projects {
+				if m.marked[p.Name] {
+					m.confirm = append(m.confirm, p)
+				}
+			}
+			if len(m.confirm) == 0 {
+				m.confirm = []finder.Project{m.filtered[m.cursor]}
+			}
+			return m, nil
 
 		default:
 			// Update text input
@@ -116,15 +333,151 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.cursor = 0
 			}
 
-			return m, cmd
+			return m, tea.Batch(cmd, m.previewCmd())
+		}
+	}
+
+	return m, cmd
+}
+
+// handleConfirm answers the kill confirmation prompt
+func (m model) handleConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	targets := m.confirm
+	m.confirm = nil
+
+	switch msg.String() {
+	case "y", "Y":
+		kill := m.opts.Kill
+		return m, func() tea.Msg {
+			var msg killedMsg
+			var errs []error
+			for _, p := range targets {
+				if err := kill(p); err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				msg.killed = append(msg.killed, p)
+			}
+			msg.err = errors.Join(errs...)
+			return msg
+		}
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	}
+	return m, nil
+}
+
+// handleRename edits the new name of the highlighted item, renaming it on
+// enter or leaving it be on esc
+func (m model) handleRename(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		m.quitting = true
+		return m, tea.Quit
+	case "esc":
+		m.renaming = false
+		return m, m.textInput.Focus()
+	case "enter":
+		m.renaming = false
+		item, name := m.filtered[m.cursor], strings.TrimSpace(m.rename.Value())
+		if name == "" || name == item.Name {
+			return m, m.textInput.Focus()
 		}
+		rename := m.opts.Rename
+		return m, tea.Batch(m.textInput.Focus(), func() tea.Msg {
+			newName, err := rename(item, name)
+			return renamedMsg{old: item.Name, name: newName, err: err}
+		})
 	}
 
+	var cmd tea.Cmd
+	m.rename, cmd = m.rename.Update(msg)
 	return m, cmd
 }
 
+// handleRenamed updates the list after an item was renamed
+func (m model) handleRenamed(msg renamedMsg) (tea.Model, tea.Cmd) {
+	if msg.err != nil {
+		m.status = errorStyle.Render(msg.err.Error())
+		return m, nil
+	}
+	m.status = fmt.Sprintf("Renamed %s to %s", msg.old, msg.name)
+
+	projects := slices.Clone(m.projects)
+	for i := range projects {
+		if projects[i].Name == msg.old {
+			projects[i].Name = msg.name
+		}
+	}
+	if m.marked[msg.old] {
+		delete(m.marked, msg.old)
+		m.marked[msg.name] = true
+	}
+	m.setProjects(projects)
+	return m, nil
+}
+
+// handleKilled drops killed items from the list
+func (m model) handleKilled(msg killedMsg) (tea.Model, tea.Cmd) {
+	m.status = fmt.Sprintf("Killed %d", len(msg.killed))
+	if msg.err != nil {
+		m.status = errorStyle.Render(msg.err.Error())
+	}
+
+	gone := make(map[string]bool)
+	for _, p := range msg.killed {
+		gone[p.Name] = true
+	}
+	m.marked = make(map[string]bool)
+
+	var remaining []finder.Project
+	for _, p := range m.projects {
+		if !gone[p.Name] {
+			remaining = append(remaining, p)
+		}
+	}
+	if len(remaining) == 0 {
+		m.quitting = true
+		return m, tea.Quit
+	}
+	m.setProjects(remaining)
+	return m, m.previewCmd()
+}
+
+// setProjects replaces the project list while keeping the cursor on the same
+// project (by path) even if the list was reordered or grew
+func (m *model) setProjects(projects []finder.Project) {
+	var selectedPath string
+	if m.cursor < len(m.filtered) {
+		selectedPath = m.filtered[m.cursor].Path
+	}
+
+	m.projects = projects
+	if query := m.textInput.Value(); query == "" {
+		m.filtered = m.projects
+	} else {
+		m.filtered = m.fuzzyFilter(query)
+	}
+
+	m.cursor = 0
+	for i, p := range m.filtered {
+		if p.Path == selectedPath {
+			m.cursor = i
+			break
+		}
+	}
+}
+
+// fuzzyFilter returns the projects whose names match query, best first,
+// after any project whose short code is exactly query
 func (m model) fuzzyFilter(query string) []finder.Project {
 	var matches []finder.Project
+	for _, p := range m.projects {
+		if p.Code != "" && strings.EqualFold(p.Code, query) {
+			matches = append(matches, p)
+		}
+	}
 
 	// Create a slice of project names for fuzzy matching
 	names := make([]string, len(m.projects))
@@ -137,7 +490,10 @@ func (m model) fuzzyFilter(query string) []finder.Project {
 
 	// Build filtered list maintaining original project data
 	for _, result := range results {
-		matches = append(matches, m.projects[result.Index])
+		// Projects with the code as query are already first
+		if p := m.projects[result.Index]; p.Code == "" || !strings.EqualFold(p.Code, query) {
+			matches = append(matches, p)
+		}
 	}
 
 	return matches
@@ -150,58 +506,54 @@ func (m model) View() string {
 
 	var s strings.Builder
 
-	// Title
-	s.WriteString(titleStyle.Render("Select a project"))
-	s.WriteString("\n\n")
-
-	// Search input
-	s.WriteString(m.textInput.View())
-	s.WriteString("\n\n")
+	header := titleStyle.Render("Select a project") + "\n\n" + m.textInput.View() + "\n\n"
 
 	// Error message if no projects
 	if len(m.projects) == 0 {
+		s.WriteString(header)
 		s.WriteString(errorStyle.Render("No Git projects found!"))
 		s.WriteString("\n")
 		s.WriteString(helpStyle.Render("Make sure you have Git projects in your configured directories."))
 		s.WriteString("\n\n")
-		s.WriteString(helpStyle.Render("Press Esc or Ctrl+C to quit"))
+		s.WriteString(helpStyle.Render("Press " + strings.Join(append(slices.Clone(keys["quit"]), "ctrl+c"), " or ") + " to quit"))
 		return s.String()
 	}
 
 	// No matches message
 	if len(m.filtered) == 0 {
+		s.WriteString(header)
 		s.WriteString(errorStyle.Render("No matches found"))
 		s.WriteString("\n\n")
-		s.WriteString(helpStyle.Render("↑/k up • ↓/j down • enter select • esc quit"))
+		s.WriteString(m.renderHelp())
 		return s.String()
 	}
 
-	// Calculate how many items we can show
-	maxItems := m.height - 10 // Account for header, input, and help text
-	if maxItems < 5 {
-		maxItems = 5
-	}
+	footer := m.renderPreview() + "\n" + m.renderHelp()
 
-	// Calculate visible range
-	start := 0
-	end := len(m.filtered)
+	height := m.height
+	if height == 0 {
+		height = defaultHeight
+	}
 
-	if len(m.filtered) > maxItems {
-		// Center the cursor in the view
-		start = m.cursor - maxItems/2
-		if start < 0 {
-			start = 0
-		}
-		end = start + maxItems
-		if end > len(m.filtered) {
-			end = len(m.filtered)
-			start = end - maxItems
-			if start < 0 {
-				start = 0
-			}
+	// Small terminals (tmux popups are often tiny): drop the title, preview
+	// and help so the list itself stays visible
+	if lipgloss.Height(header)+lipgloss.Height(footer)+minListLines > height {
+		header = m.textInput.View() + "\n"
+		footer = ""
+		// The prompt must stay visible or the next key press is a surprise
+		if m.confirm != nil {
+			footer = m.renderHelp()
 		}
 	}
 
+	available := height - lipgloss.Height(header)
+	if footer != "" {
+		available -= lipgloss.Height(footer)
+	}
+	start, end := m.visibleRange(available)
+
+	s.WriteString(header)
+
 	// Show indicator if there are more items above
 	if start > 0 {
 		s.WriteString(helpStyle.Render(fmt.Sprintf("... %d more above ...", start)))
@@ -210,26 +562,7 @@ func (m model) View() string {
 
 	// Project list
 	for i := start; i < end; i++ {
-		project := m.filtered[i]
-
-		cursor := "  "
-		if i == m.cursor {
-			cursor = "> "
-		}
-
-		name := project.Name
-		path := project.Path
-
-		if i == m.cursor {
-			s.WriteString(cursor + selectedStyle.Render(name))
-			s.WriteString("\n")
-			s.WriteString("  " + pathStyle.Render(path))
-		} else {
-			s.WriteString(cursor + normalStyle.Render(name))
-			s.WriteString("\n")
-			s.WriteString("  " + pathStyle.Render(path))
-		}
-
+		s.WriteString(m.renderItem(i))
 		s.WriteString("\n")
 	}
 
@@ -239,16 +572,185 @@ func (m model) View() string {
 		s.WriteString("\n")
 	}
 
-	// Help text
-	s.WriteString("\n")
-	s.WriteString(helpStyle.Render("↑/k up • ↓/j down • enter select • esc quit"))
+	s.WriteString(footer)
+
+	return strings.TrimSuffix(s.String(), "\n")
+}
+
+// renderItem renders the project at index i: its name, then its path. Both
+// are truncated to the terminal width so every item is exactly two lines.
+func (m model) renderItem(i int) string {
+	project := m.filtered[i]
+	name, path := project.Name, project.Path
+
+	detail := ""
+	if project.Detail != "" {
+		detail = " • " + project.Detail
+	}
+
+	// The short code follows the name, dimmed
+	code := ""
+	if project.Code != "" {
+		code = " " + codeStyle.Render(project.Code)
+	}
+
+	if m.width > 2 {
+		name = ansi.Truncate(name, m.width-2-ansi.StringWidth(code), "…")
+		// Cut paths from the left: the project directory at the end is the
+		// part that tells similar paths apart
+		if over := ansi.StringWidth(path+detail) - (m.width - 2); over > 0 {
+			path = ansi.TruncateLeft(path, over+1, "…")
+		}
+	}
 
-	return s.String()
+	prefix := "  "
+	if m.marked[project.Name] {
+		prefix = " *"
+	}
+	if i == m.cursor && m.renaming {
+		return ">" + prefix[1:] + m.rename.View() + "\n  " + pathStyle.Render(path+detail)
+	}
+	if i == m.cursor {
+		return ">" + prefix[1:] + selectedStyle.Render(name) + code + "\n  " + pathStyle.Render(path+detail)
+	}
+	return prefix + normalStyle.Render(name) + code + "\n  " + pathStyle.Render(path+detail)
+}
+
+// renderHelp renders the key help, or the kill confirmation prompt while it
+// is open, preceded by the outcome of the last action
+func (m model) renderHelp() string {
+	if m.confirm != nil {
+		names := make([]string, len(m.confirm))
+		for i, p := range m.confirm {
+			names[i] = p.Name
+		}
+		return errorStyle.MarginTop(1).Render(fmt.Sprintf("Kill %s? y/n", strings.Join(names, ", ")))
+	}
+
+	if m.renaming {
+		return helpStyle.Render("enter rename • esc cancel")
+	}
+
+	items := []string{keyHelp("up", "up"), keyHelp("down", "down"), keyHelp("select", "select"), keyHelp("quit", "quit")}
+	if m.opts.Kill != nil {
+		items = append(items, keyHelp("mark", "mark"), keyHelp("kill-session", "kill"))
+	}
+	if m.opts.Rename != nil {
+		items = append(items, keyHelp("rename-session", "rename"))
+	}
+	if m.opts.Reload != nil {
+		items = append(items, keyHelp("reload", "reload"))
+	}
+	if m.sort != "" {
+		items = append(items, keyHelp("toggle-sort", "sort"))
+	}
+	help := strings.Join(slices.DeleteFunc(items, func(s string) bool { return s == "" }), " • ")
+	if m.status != "" {
+		help = m.status + "\n" + help
+	}
+	return helpStyle.Render(help)
+}
+
+// visibleRange returns the range of items that fit in the given number of
+// lines, keeping the cursor roughly centred. At least the cursor's item is
+// always shown.
+func (m model) visibleRange(lines int) (int, int) {
+	heights := make([]int, len(m.filtered))
+	total := 0
+	for i := range m.filtered {
+		heights[i] = lipgloss.Height(m.renderItem(i))
+		total += heights[i]
+	}
+	if total <= lines {
+		return 0, len(m.filtered)
+	}
+
+	// Leave room for the "more above/below" indicators
+	budget := lines - 2*lipgloss.Height(helpStyle.Render("..."))
+	start, end := m.cursor, m.cursor+1
+	used := heights[m.cursor]
+
+	for {
+		grew := false
+		if end < len(m.filtered) && used+heights[end] <= budget {
+			used += heights[end]
+			end++
+			grew = true
+		}
+		if start > 0 && used+heights[start-1] <= budget {
+			start--
+			used += heights[start]
+			grew = true
+		}
+		if !grew {
+			return start, end
+		}
+	}
+}
+
+// renderPreview renders the preview pane for the highlighted project
+func (m model) renderPreview() string {
+	p, ok := m.previews[m.filtered[m.cursor].Path]
+	if !ok {
+		return ""
+	}
+	if p == nil {
+		return previewStyle.Render("Loading…")
+	}
+
+	var lines []string
+	if p.summary.Description != "" {
+		lines = append(lines, p.summary.Description)
+	}
+	if langs := p.summary.LanguageString(); langs != "" {
+		lines = append(lines, langs)
+	}
+	for _, subject := range p.summary.Commits {
+		lines = append(lines, "• "+subject)
+	}
+	if p.history.Opens > 0 {
+		lines = append(lines, fmt.Sprintf("Opened %s, last %s", plural(p.history.Opens, "time"),
+			preview.FormatAge(time.Since(p.history.LastOpened))))
+	}
+	status := fmt.Sprintf("TODO %d • FIXME %d • stashes %d",
+		p.health.Todos, p.health.Fixmes, p.health.Stashes)
+	if p.size != nil {
+		status += fmt.Sprintf(" • %s in %d files", preview.FormatBytes(p.size.Bytes), p.size.Files)
+	}
+	lines = append(lines, status)
+
+	// Keep each line to one row so the layout maths stays right
+	if m.width > 0 {
+		for i, line := range lines {
+			lines[i] = ansi.Truncate(line, m.width, "…")
+		}
+	}
+	return previewStyle.Render(strings.Join(lines, "\n"))
+}
+
+// plural formats a count with a noun, e.g. "1 time" or "14 times"
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
 }
 
 // SelectProject displays a TUI for selecting a project and returns the selected project
 func SelectProject(projects []finder.Project) (*finder.Project, error) {
-	p := tea.NewProgram(initialModel(projects))
+	return SelectProjectWithOptions(projects, Options{})
+}
+
+// SelectProjectWithOptions is SelectProject with optional picker features enabled
+func SelectProjectWithOptions(projects []finder.Project, opts Options) (*finder.Project, error) {
+	var programOpts []tea.ProgramOption
+	// Draw on stderr when stdout is captured, e.g. dir=$(sesh pick --print)
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		programOpts = append(programOpts, tea.WithOutput(os.Stderr))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := tea.NewProgram(initialModel(ctx, projects, opts), programOpts...)
 
 	m, err := p.Run()
 	if err != nil {
@@ -0,0 +1,98 @@
+package ui
+
+import (
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// palette holds the colours the picker is drawn with
+type palette struct {
+	title, selected, selectedText, text, path, help, err, preview, border string
+}
+
+// presets are the palettes behind config.ThemePresets
+var presets = map[string]palette{
+	"dark": {
+		title: "#7D56F4", selected: "#7D56F4", selectedText: "#FFFFFF", text: "#FFFFFF",
+		path: "#888888", help: "#626262", err: "#FF0000", preview: "#AAAAAA", border: "#444444",
+	},
+	"light": {
+		title: "#5A32E0", selected: "#7D56F4", selectedText: "#FFFFFF", text: "#1A1A1A",
+		path: "#6C6C6C", help: "#8A8A8A", err: "#D70000", preview: "#4E4E4E", border: "#BCBCBC",
+	},
+}
+
+var (
+	titleStyle    lipgloss.Style
+	selectedStyle lipgloss.Style
+	normalStyle   lipgloss.Style
+	pathStyle     lipgloss.Style
+	codeStyle     lipgloss.Style
+	helpStyle     lipgloss.Style
+	errorStyle    lipgloss.Style
+	previewStyle  lipgloss.Style
+)
+
+func init() {
+	applyPalette(presets["dark"])
+}
+
+// SetTheme colours the picker with the theme's preset and any colours it
+// sets on top
+func SetTheme(theme config.Theme) {
+	p, ok := presets[theme.Preset]
+	if !ok {
+		p = presets["dark"]
+	}
+	for _, c := range []struct {
+		dst *string
+		src string
+	}{
+		{&p.title, theme.Title}, {&p.selected, theme.Selected}, {&p.selectedText, theme.SelectedText},
+		{&p.text, theme.Text}, {&p.path, theme.Path}, {&p.help, theme.Help},
+	} {
+		if c.src != "" {
+			*c.dst = c.src
+		}
+	}
+	applyPalette(p)
+}
+
+// applyPalette builds the picker's styles from p
+func applyPalette(p palette) {
+	titleStyle = lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color(p.title)).
+		MarginBottom(1)
+
+	selectedStyle = lipgloss.NewStyle().
+		Background(lipgloss.Color(p.selected)).
+		Foreground(lipgloss.Color(p.selectedText)).
+		Bold(true)
+
+	normalStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.text))
+
+	pathStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.path)).
+		Italic(true)
+
+	codeStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.help)).
+		Faint(true)
+
+	helpStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.help)).
+		MarginTop(1)
+
+	errorStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.err)).
+		Bold(true)
+
+	previewStyle = lipgloss.NewStyle().
+		Foreground(lipgloss.Color(p.preview)).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderTop(true).
+		BorderForeground(lipgloss.Color(p.border)).
+		MarginTop(1)
+}
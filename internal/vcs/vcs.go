@@ -0,0 +1,149 @@
+package vcs
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/git"
+)
+
+// Detector recognises working copies of one version control system and
+// reads what the project preview shows from them
+type Detector interface {
+	// Name is the system's command, such as git or hg
+	Name() string
+
+	// Marker is the directory at the root of a working copy, such as .git
+	Marker() string
+
+	// Files lists the files tracked in the working copy at dir, relative
+	// to it
+	Files(dir string) ([]string, error)
+
+	// Log returns the subjects of the latest n commits, newest first. It
+	// is empty for systems that would have to ask a server.
+	Log(dir string, n int) ([]string, error)
+}
+
+// detectors are the supported systems. Jujutsu comes before Git, since
+// Jujutsu repositories colocated with Git have both markers.
+var detectors = []Detector{jujutsu{}, gitRepo{}, mercurial{}, subversion{}}
+
+// Markers returns the marker directories of the supported systems
+func Markers() []string {
+	markers := make([]string, len(detectors))
+	for i, d := range detectors {
+		markers[i] = d.Marker()
+	}
+	return markers
+}
+
+// IsMarker reports whether name is the marker directory of a supported system
+func IsMarker(name string) bool {
+	for _, d := range detectors {
+		if d.Marker() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Detect returns the system managing the working copy rooted at dir. Unlike
+// the project search it takes a .git file, as in a worktree, as a repository.
+func Detect(dir string) (Detector, bool) {
+	for _, d := range detectors {
+		if _, err := os.Stat(filepath.Join(dir, d.Marker())); err == nil {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+type gitRepo struct{}
+
+func (gitRepo) Name() string   { return "git" }
+func (gitRepo) Marker() string { return ".git" }
+
+func (gitRepo) Files(dir string) ([]string, error) {
+	return git.Lines(dir, "ls-files")
+}
+
+func (gitRepo) Log(dir string, n int) ([]string, error) {
+	return git.Lines(dir, "log", "-"+strconv.Itoa(n), "--format=%s")
+}
+
+type mercurial struct{}
+
+func (mercurial) Name() string   { return "hg" }
+func (mercurial) Marker() string { return ".hg" }
+
+func (mercurial) Files(dir string) ([]string, error) {
+	return lines(dir, "hg", "files")
+}
+
+func (mercurial) Log(dir string, n int) ([]string, error) {
+	return lines(dir, "hg", "log", "--limit", strconv.Itoa(n), "--template", "{desc|firstline}\n")
+}
+
+type jujutsu struct{}
+
+func (jujutsu) Name() string   { return "jj" }
+func (jujutsu) Marker() string { return ".jj" }
+
+// Files lists the files of the working-copy commit. Like Log it leaves the
+// working copy alone rather than snapshotting it, which a preview shouldn't do.
+func (jujutsu) Files(dir string) ([]string, error) {
+	return lines(dir, "jj", "--ignore-working-copy", "file", "list")
+}
+
+func (jujutsu) Log(dir string, n int) ([]string, error) {
+	// The working-copy commit usually has no description yet
+	return lines(dir, "jj", "--ignore-working-copy", "log", "--no-graph", "-r", "::@ ~ empty()", "-n", strconv.Itoa(n),
+		"-T", `description.first_line() ++ "\n"`)
+}
+
+type subversion struct{}
+
+func (subversion) Name() string   { return "svn" }
+func (subversion) Marker() string { return ".svn" }
+
+func (subversion) Files(dir string) ([]string, error) {
+	// Verbose status lists every versioned path, last on its line
+	status, err := lines(dir, "svn", "status", "--verbose", "--quiet")
+	if err != nil {
+		return nil, err
+	}
+	files := make([]string, 0, len(status))
+	for _, line := range status {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			files = append(files, fields[len(fields)-1])
+		}
+	}
+	return files, nil
+}
+
+// Log is empty, as svn log asks the server
+func (subversion) Log(string, int) ([]string, error) {
+	return nil, nil
+}
+
+// lines runs a command in dir and returns its non-empty output lines
+func lines(dir, name string, args ...string) ([]string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	var result []string
+	for _, line := range strings.Split(string(output), "\n") {
+		if line = strings.TrimRight(line, "\r"); line != "" {
+			result = append(result, line)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,59 @@
+package xdg
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// private limits sesh's files to the current user, see SetPrivate
+var private bool
+
+// SetPrivate controls whether sesh writes its files as 0600 and its
+// directories as 0700, or as 0644 and 0755. The umask applies either way.
+func SetPrivate(on bool) {
+	private = on
+}
+
+// FileMode returns the permissions for files sesh writes
+func FileMode() os.FileMode {
+	if private {
+		return 0600
+	}
+	return 0644
+}
+
+// DirMode returns the permissions for directories sesh creates
+func DirMode() os.FileMode {
+	if private {
+		return 0700
+	}
+	return 0755
+}
+
+// TightenPermissions removes group and other access from everything under
+// the config, cache and state directories, fixing files written by older
+// versions. Once every base directory is private there is nothing left to do,
+// so later calls only cost a stat each. Paths that can't be changed, such as
+// a read-only config, are left alone.
+func TightenPermissions() {
+	for _, base := range []func() (string, error){ConfigDir, CacheDir, StateDir} {
+		root, err := base()
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(root)
+		if err != nil || info.Mode().Perm()&0077 == 0 {
+			continue
+		}
+		_ = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.Type()&fs.ModeSymlink != 0 {
+				return nil
+			}
+			if info, err := d.Info(); err == nil && info.Mode().Perm()&0077 != 0 {
+				_ = os.Chmod(path, info.Mode().Perm()&^0077)
+			}
+			return nil
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package xdg
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// App is the directory name used under each base directory
+const App = "sesh"
+
+// ConfigDir returns $XDG_CONFIG_HOME/sesh, or ~/.config/sesh
+func ConfigDir() (string, error) {
+	return dir("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheDir returns $XDG_CACHE_HOME/sesh, or ~/.cache/sesh
+func CacheDir() (string, error) {
+	return dir("XDG_CACHE_HOME", ".cache")
+}
+
+// StateDir returns $XDG_STATE_HOME/sesh, or ~/.local/state/sesh
+func StateDir() (string, error) {
+	return dir("XDG_STATE_HOME", ".local", "state")
+}
+
+// dir resolves a base directory from env, falling back to a path under the
+// home directory. The fallback is the same on every platform, including
+// macOS, where tools like sesh conventionally live in ~/.config rather than
+// ~/Library. The spec says relative values must be ignored.
+func dir(env string, fallback ...string) (string, error) {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, App), nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(append(append([]string{home}, fallback...), App)...), nil
+}
@@ -1,7 +1,10 @@
 package zoxide
 
 import (
+	"os"
 	"os/exec"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -12,12 +15,45 @@ type Score struct {
 	Score float64
 }
 
-// IsAvailable checks if zoxide is installed
+// disabled is set when the user turned the zoxide integration off
+var disabled bool
+
+// Disable stops sesh from reading or writing the zoxide database, as if
+// zoxide wasn't installed
+func Disable() {
+	disabled = true
+}
+
+// Disabled reports whether the integration was turned off with Disable
+func Disabled() bool {
+	return disabled
+}
+
+// IsAvailable checks if zoxide is installed and the integration is enabled
 func IsAvailable() bool {
+	if disabled {
+		return false
+	}
 	_, err := exec.LookPath("zoxide")
 	return err == nil
 }
 
+// DatabasePath returns the file zoxide keeps its scores in: db.zo in
+// $_ZO_DATA_DIR, else in the platform's data directory as zoxide picks it
+func DatabasePath() string {
+	if dir := os.Getenv("_ZO_DATA_DIR"); dir != "" {
+		return filepath.Join(dir, "db.zo")
+	}
+	home, _ := os.UserHomeDir()
+	data := filepath.Join(home, ".local", "share")
+	if runtime.GOOS == "darwin" {
+		data = filepath.Join(home, "Library", "Application Support")
+	} else if xdgData := os.Getenv("XDG_DATA_HOME"); filepath.IsAbs(xdgData) {
+		data = xdgData
+	}
+	return filepath.Join(data, "zoxide", "db.zo")
+}
+
 // GetScores returns zoxide scores for all tracked directories
 func GetScores() (map[string]float64, error) {
 	if !IsAvailable() {
@@ -67,6 +103,16 @@ func Add(path string) error {
 	return cmd.Run()
 }
 
+// Remove removes a path from the zoxide database
+func Remove(path string) error {
+	if !IsAvailable() {
+		return nil
+	}
+
+	cmd := exec.Command("zoxide", "remove", path)
+	return cmd.Run()
+}
+
 // GetScore returns the zoxide score for a specific path
 func GetScore(path string) float64 {
 	scores, err := GetScores()
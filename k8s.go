@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/kube"
+	"github.com/adamflitney/sesh/internal/tmux"
+	"github.com/adamflitney/sesh/internal/ui"
+)
+
+func runK8s() error {
+	if !kube.IsAvailable() {
+		return fmt.Errorf("kubectl is not installed")
+	}
+
+	contexts, err := kube.Contexts()
+	if err != nil {
+		return err
+	}
+	if len(contexts) == 0 {
+		return fmt.Errorf("no kubectl contexts configured")
+	}
+
+	choices := make([]finder.Project, 0, len(contexts))
+	for _, c := range contexts {
+		choices = append(choices, finder.Project{Name: c})
+	}
+
+	selected, err := ui.SelectProject(choices)
+	if err != nil {
+		return fmt.Errorf("failed to select context: %w", err)
+	}
+	if selected == nil {
+		return nil
+	}
+
+	kubeconfig, err := kube.WriteContextConfig(selected.Name)
+	if err != nil {
+		return err
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	// One session per context, with KUBECONFIG pinned to that context
+	session := finder.Project{Name: "k8s-" + selected.Name, Path: home, Session: "k8s-" + selected.Name}
+	layout := tmux.Layout{
+		Windows: []tmux.Window{{Name: "kubectl"}},
+		Env:     map[string]string{"KUBECONFIG": kubeconfig},
+	}
+	return tmux.OpenSession(session, layout)
+}
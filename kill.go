@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/daemon"
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+func runKill(args []string) error {
+	all := false
+	var names []string
+	for _, arg := range args {
+		switch arg {
+		case "-a", "--all":
+			all = true
+		default:
+			names = append(names, arg)
+		}
+	}
+	if all == (len(names) > 0) {
+		return fmt.Errorf("usage: sesh kill <name> | sesh kill --all")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if all {
+		return killAllSessions(cfg.SnapshotOnKill)
+	}
+
+	sessionName, err := resolveSession(cfg, strings.Join(names, " "))
+	if err != nil {
+		return err
+	}
+	if err := tmux.KillSession(sessionName, cfg.SnapshotOnKill); err != nil {
+		return err
+	}
+	fmt.Printf("Killed session %s\n", sessionName)
+	return nil
+}
+
+// resolveSession finds the running session for a name given on the command
+// line: a session of that name, else the session of the project it names,
+// matched as sesh connect does
+func resolveSession(cfg *config.Config, name string) (string, error) {
+	if exists, _ := tmux.SessionExists(name); exists {
+		return name, nil
+	}
+
+	projects, err := daemon.FetchProjects()
+	if err != nil {
+		if projects, err = finder.FindGitProjects(context.Background(), cfg.ProjectDirectories, cfg.Projects, cfg.Sort); err != nil {
+			return "", err
+		}
+	}
+	project, ok := matchProject(projects, name)
+	if !ok {
+		return "", fmt.Errorf("no session or project named %s", name)
+	}
+	sessionName, ok := tmux.ProjectSession(project)
+	if !ok {
+		return "", fmt.Errorf("project %s has no running session", project.Name)
+	}
+	return sessionName, nil
+}
+
+// killAllSessions kills every session sesh created, leaving other sessions
+// alone. The current session goes last, as sesh runs inside it.
+func killAllSessions(snapshot bool) error {
+	sessions, err := tmux.ListSessionInfo(context.Background())
+	if err != nil {
+		// tmux not running
+		sessions = nil
+	}
+
+	current := tmux.CurrentSession()
+	var names []string
+	killCurrent := false
+	for _, s := range sessions {
+		switch {
+		case s.Project == "":
+			continue
+		case s.Name == current:
+			killCurrent = true
+		default:
+			names = append(names, s.Name)
+		}
+	}
+	if killCurrent {
+		names = append(names, current)
+	}
+	if len(names) == 0 {
+		fmt.Println("No sessions created by sesh are running")
+		return nil
+	}
+
+	for _, name := range names {
+		if err := tmux.KillSession(name, snapshot); err != nil {
+			return err
+		}
+		fmt.Printf("Killed session %s\n", name)
+	}
+	return nil
+}
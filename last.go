@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// runLast switches back to the session sesh switched away from last, so
+// running it again toggles between the two
+func runLast(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: sesh last")
+	}
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	sessionName, err := tmux.PreviousSession()
+	if err != nil {
+		return err
+	}
+	if os.Getenv("TMUX") == "" && os.Getenv("SESH_TARGET_CLIENT") == "" {
+		return tmux.AttachSession(sessionName)
+	}
+	return tmux.SwitchSession(sessionName)
+}
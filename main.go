@@ -1,48 +1,147 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"strings"
+	"time"
+	"unicode/utf8"
 
 	"github.com/adamflitney/sesh/internal/cache"
 	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/daemon"
 	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/logging"
+	"github.com/adamflitney/sesh/internal/preview"
 	"github.com/adamflitney/sesh/internal/tmux"
 	"github.com/adamflitney/sesh/internal/ui"
+	"github.com/adamflitney/sesh/internal/xdg"
 	"github.com/adamflitney/sesh/internal/zoxide"
+	"github.com/sahilm/fuzzy"
 )
 
 func main() {
 	if err := run(); err != nil {
+		slog.Error("command failed", "args", os.Args[1:], "err", err)
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
 
 func run() error {
+	args, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		return err
+	}
+	profile := config.ActiveProfile()
+	if strings.ContainsRune(profile, os.PathSeparator) {
+		return fmt.Errorf("invalid profile name: %s", profile)
+	}
+	cache.SetProfile(profile)
+	// Ask for layout variables that weren't passed with --var, and what to
+	// do about sessions that clash with the project being opened
+	if isTerminal() {
+		tmux.SetVarPrompt(promptVar)
+		tmux.SetConflictPrompt(promptConflict)
+	}
+
+	// Plain sesh list is run from prompts and shell completion, so answer it
+	// from the last result when nothing changed, before loading the config
+	if len(args) == 1 && args[0] == "list" && printCachedList() {
+		return nil
+	}
+
 	// Parse subcommands
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if len(args) > 0 {
+		switch args[0] {
 		case "list":
-			return runList(os.Args[2:])
+			return runList(args[1:])
 		case "connect":
-			if len(os.Args) < 3 {
+			args, err := parseVars(args)
+			if err == nil {
+				args, err = parseConnectFlags(args)
+			}
+			if err != nil {
+				return err
+			}
+			if len(args) < 2 {
+				// Without a name, let the user pick one when we can show the TUI
+				if isTerminal() {
+					return runInteractive()
+				}
 				return fmt.Errorf("usage: sesh connect <project-name>")
 			}
-			return runConnect(strings.Join(os.Args[2:], " "))
+			return runConnect(strings.Join(args[1:], " "))
 		case "switch":
-			return runSwitch()
+			return runSwitch(args[1:])
+		case "pick":
+			return runPick(args[1:])
+		case "status":
+			return runStatus()
+		case "dirs":
+			return runDirs(args[1:])
+		case "config":
+			return runConfig(args[1:])
+		case "ssh":
+			return runSSH()
+		case "k8s":
+			return runK8s()
+		case "undo":
+			if _, err := loadConfig(); err != nil {
+				return err
+			}
+			return tmux.RestoreLastKilled()
+		case "snapshot":
+			return runSnapshot(args[1:])
+		case "restore":
+			return runRestore(args[1:])
+		case "run":
+			return runRun(args[1:])
+		case "archive":
+			return runArchive(args[1:])
+		case "kill":
+			return runKill(args[1:])
+		case "prune":
+			return runPrune(args[1:])
+		case "rename":
+			return runRename(args[1:])
+		case "last":
+			return runLast(args[1:])
+		case "check-startup":
+			return runCheckStartup(args[1:])
+		case "doctor":
+			return runDoctor(args[1:])
+		case "init":
+			return runInit(args[1:])
+		case "serve":
+			return runServe(args[1:])
+		case "tmux":
+			return runTmux(args[1:])
+		case "import":
+			return runImport(args[1:])
+		case "templates":
+			return runTemplates(args[1:])
+		case "widget":
+			return runWidget(args[1:])
+		case "completion":
+			return runCompletion(args[1:])
 		case "help", "-h", "--help":
 			printUsage()
 			return nil
 		case "version", "-v", "--version":
-			fmt.Println("sesh v0.2.0")
-			return nil
+			return runVersion(args[1:])
+		case "--":
+			// Explicit quick connect, works even with quick_connect disabled
+			if len(args) < 2 {
+				return fmt.Errorf("usage: sesh -- <project-name>")
+			}
+			return runConnect(strings.Join(args[1:], " "))
 		default:
 			// Unknown subcommand - treat as project name for quick connect
-			return runConnect(strings.Join(os.Args[1:], " "))
+			return runQuickConnect(args)
 		}
 	}
 
@@ -50,19 +149,229 @@ func run() error {
 	return runInteractive()
 }
 
+// runQuickConnect connects to the project named by args unless quick connect
+// is disabled, in which case an unknown command is an error
+func runQuickConnect(args []string) error {
+	args, err := parseVars(args)
+	if err == nil {
+		args, err = parseConnectFlags(args)
+	}
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return runInteractive()
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	if !cfg.QuickConnect {
+		return fmt.Errorf("unknown command: %s\n\nRun 'sesh help' for the list of commands, or 'sesh -- %s' to connect to a project",
+			args[0], strings.Join(args, " "))
+	}
+	return runConnect(strings.Join(args, " "))
+}
+
+// noZoxide is set by --no-zoxide, as opposed to zoxide: false in the config
+var noZoxide bool
+
+// parseGlobalFlags consumes flags that apply to every command, which must
+// come before the subcommand, and returns the remaining arguments
+func parseGlobalFlags(args []string) ([]string, error) {
+	for len(args) > 0 {
+		switch {
+		case args[0] == "--config":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("--config requires a file path")
+			}
+			config.SetConfigFile(args[1])
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--config="):
+			config.SetConfigFile(strings.TrimPrefix(args[0], "--config="))
+			args = args[1:]
+		case args[0] == "--profile":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("--profile requires a profile name")
+			}
+			config.SetProfile(args[1])
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--profile="):
+			config.SetProfile(strings.TrimPrefix(args[0], "--profile="))
+			args = args[1:]
+		case args[0] == "--socket-name" || args[0] == "--socket-path":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("%s requires a value", args[0])
+			}
+			setSocket(args[0], args[1])
+			args = args[2:]
+		case strings.HasPrefix(args[0], "--socket-name=") || strings.HasPrefix(args[0], "--socket-path="):
+			flag, value, _ := strings.Cut(args[0], "=")
+			setSocket(flag, value)
+			args = args[1:]
+		case args[0] == "--no-zoxide":
+			zoxide.Disable()
+			noZoxide = true
+			args = args[1:]
+		default:
+			return args, nil
+		}
+	}
+	return args, nil
+}
+
+// setSocket points sesh at another tmux server for --socket-name (tmux -L)
+// or --socket-path (tmux -S)
+func setSocket(flag, value string) {
+	if flag == "--socket-name" {
+		tmux.SetSocket(value, "")
+	} else {
+		tmux.SetSocket("", value)
+	}
+}
+
+// loadConfig loads the user configuration and applies it to the packages
+// that need it
+func loadConfig() (*config.Config, error) {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+	if err := applyConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// applyConfig hands a loaded config to the packages that need it
+func applyConfig(cfg *config.Config) error {
+	xdg.SetPrivate(cfg.PrivateFiles)
+	if cfg.PrivateFiles {
+		xdg.TightenPermissions()
+	}
+	if err := logging.Init(cfg.LogLevel); err != nil {
+		return err
+	}
+	tmux.SetConfig(cfg)
+	finder.SetRanking(cfg.Ranking)
+	finder.SetScoreCommand(cfg.ScoreCommand)
+	finder.SetCodes(cfg.ProjectCodes())
+	cache.SetRecentSize(cfg.Ranking.RecentSize)
+	ui.SetTheme(cfg.Theme)
+	ui.SetKeys(cfg.Keys)
+	if !cfg.Zoxide {
+		zoxide.Disable()
+	}
+	return nil
+}
+
 func printUsage() {
 	fmt.Println(`sesh - Smart tmux session manager
 
 Usage:
+  sesh [--config <file>] [--profile <name>] [--socket-name <name> | --socket-path <path>]
+       [--no-zoxide] <command>
+
+  --config <file>       Use this config file (default: $SESH_CONFIG, then
+                        ~/.config/sesh/config.yaml)
+  --profile <name>      Apply a profile from the config (default: $SESH_PROFILE)
+  --socket-name <name>  Use the tmux server on this socket name, as tmux -L
+  --socket-path <path>  Use the tmux server on this socket path, as tmux -S
+                        (default for both: tmux_socket in the config, then
+                        the server sesh runs in)
+  --no-zoxide           Neither rank by nor record visits in zoxide
+
+Commands:
   sesh                  Interactive project picker (TUI)
   sesh list             List all projects (one per line)
+  sesh list --refresh   List projects, ignoring the result cached for a minute
   sesh list -t          List only active tmux sessions
+  sesh list -t --clients
+                        Also show the ttys of clients attached to each session
   sesh list --json      List projects as JSON
-  sesh connect <name>   Connect to project by name
+  sesh list -l          List projects with the size of their working tree
+  sesh connect [name]   Connect to project by name (picker if omitted)
+  sesh connect <dir>    Connect to a directory given as ./dir, ~/dir or /dir,
+                        whether or not it is in a project directory
+  sesh connect <name> --var key=value
+                        Set a {{key}} variable used in the layout
+  sesh connect <name>:<window>
+                        Land on a window, by name or index, instead of the
+                        session's current one (also --window <window>)
+  sesh connect <name> --group
+                        If the session is attached elsewhere, open a grouped
+                        session sharing its windows, to show another window
   sesh switch           Interactive picker for active sessions only
+  sesh switch --client <tty> [session]
+                        Switch the client on <tty> instead of the current one
+  sesh switch --root <dir>
+                        Only offer sessions whose directory is under <dir>
+  sesh last             Switch back to the session sesh switched away from last
+  sesh pick --print     Pick a project and print its path instead of opening it
+                        (--name prints the name)
+  sesh init [dir...]    Write an annotated config, asking where projects live
+                        (--force replaces an existing config)
+  sesh status           Overview of sessions, clients and cache state
+  sesh dirs             List configured project directories
+  sesh dirs add <path>  Add a project directory to the config
+  sesh dirs rm <path>   Remove a project directory from the config
+  sesh config show      Print the resolved configuration (--json for JSON; also
+                        'sesh config dump')
+  sesh config path      Print the path of the config file in use
+  sesh config edit      Open the config file in $EDITOR, then validate it
+  sesh config validate  Check the config for unknown keys and missing directories
+  sesh templates sync [git-url]
+                        Clone or update a repository of shared session templates
+  sesh templates list   List session templates and where they come from
+  sesh import tmuxinator|tmuxp [path]
+                        Convert tmuxinator or tmuxp project files into session
+                        templates (--force replaces existing ones, --dry-run
+                        only lists them)
+  sesh ssh              Pick a host from ~/.ssh/config and open a session for it
+  sesh k8s              Pick a kubectl context and open a session pinned to it
+  sesh undo             Recreate the session sesh most recently killed
+  sesh snapshot save [file]
+                        Record every sesh session, its windows and layouts
+  sesh snapshot restore [file]
+                        Recreate the recorded sessions that aren't running
+  sesh snapshot save --auto
+                        Update the snapshot if auto_snapshot is on (for tmux hooks)
+  sesh restore --auto   Restore the snapshot once the tmux server starts, with
+                        auto_snapshot on (for run-shell in tmux.conf)
+  sesh run <name> -- <command>
+                        Run a command in a detached, tracked session
+  sesh run --list       Show jobs started with sesh run and their status
+  sesh kill <name>      Kill the session of a project or session name
+  sesh kill --all       Kill every session sesh created
+  sesh rename <old> <new>
+                        Rename a session, found as sesh kill finds it
+  sesh prune            Kill sessions whose directory is gone and drop deleted
+                        projects from the cache (--dry-run to only list them)
+  sesh check-startup <session>
+                        Report windows whose startup command failed (run
+                        automatically after a session is created)
+  sesh archive <name>   Kill a project's session and retire it (move or hide)
+  sesh doctor           Check the setup and find cached projects that no longer exist
+  sesh doctor --prune   Also remove missing projects from the cache
+  sesh doctor --frecency [--fix]
+                        Also compare zoxide's scores with the projects sesh
+                        tracks, with --fix removing and adding entries to match
+  sesh serve            Run a background daemon that keeps scan results warm
+  sesh serve --stats    Show scan timing, request counts and cache hit rate
+  sesh serve --reload   Have the daemon reload its config and rescan (as SIGHUP does)
+  sesh tmux <args>      Run tmux against the server sesh manages
+  sesh widget recent [--max N] [--current <session>]
+                        Print recent projects for the tmux status line
+  sesh widget click <range> [client]
+                        Open the recent project clicked in the status line
   sesh <name>           Quick connect (same as 'sesh connect <name>')
+  sesh -- <name>        Quick connect, also when quick_connect is off in the config
   sesh help             Show this help
-  sesh version          Show version
+  sesh completion bash|zsh|fish
+                        Print a shell completion script for project names,
+                        and directories after sesh connect
+  sesh version          Show version, commit and build date (--json for JSON)
 
 Examples:
   sesh                  # Open interactive picker
@@ -71,35 +380,65 @@ Examples:
   sesh switch           # Quick switch between open projects`)
 }
 
+// listCacheTTL is how long sesh list answers from its last result
+const listCacheTTL = time.Minute
+
 func runList(args []string) error {
 	// Parse flags
 	tmuxOnly := false
 	jsonOutput := false
+	showClients := false
+	long := false
 	for _, arg := range args {
 		switch arg {
 		case "-t", "--tmux":
 			tmuxOnly = true
+		case "--clients":
+			showClients = true
+		case "-l", "--long":
+			long = true
 		case "--json":
 			jsonOutput = true
+		case "--refresh":
+			// Only skips the cached list, see printCachedList
 		}
 	}
 
 	if tmuxOnly {
-		return listTmuxSessions()
+		return listTmuxSessions(showClients)
 	}
 
-	return listProjects(jsonOutput)
+	return listProjects(jsonOutput, long)
 }
 
-func listProjects(jsonOutput bool) error {
-	cfg, err := config.LoadConfig()
+func listProjects(jsonOutput, long bool) error {
+	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	projects, err := finder.FindGitProjects(cfg.ProjectDirectories)
+	// Prefer the daemon's warm scan results when it is running
+	projects, err := daemon.FetchProjects()
 	if err != nil {
-		return err
+		projects, err = finder.FindGitProjects(context.Background(), cfg.ProjectDirectories, cfg.Projects, cfg.Sort)
+		if err != nil {
+			return err
+		}
+	} else {
+		finder.Sort(projects, cfg.Sort)
+	}
+
+	names := make([]string, len(projects))
+	for i, p := range projects {
+		names[i] = p.Name
+	}
+	if configFile, err := config.GetConfigFilePath(); err == nil {
+		inputs := cfg.Files()
+		if !zoxide.Disabled() {
+			// Frecency follows the scores zoxide records as you cd around
+			inputs = append(inputs, zoxide.DatabasePath())
+		}
+		_ = cache.SaveProjectList(configFile, listOptions(), inputs, names)
 	}
 
 	if jsonOutput {
@@ -115,122 +454,335 @@ func listProjects(jsonOutput bool) error {
 		return nil
 	}
 
+	if long {
+		return listProjectsLong(projects)
+	}
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return nil
+}
+
+// printCachedList prints the project names from the last sesh list if they
+// are still current, reporting whether it did. The list is only trusted for
+// listCacheTTL, which bounds how stale changes sesh can't see, such as new
+// repos or score_command's output, can get.
+func printCachedList() bool {
+	configFile, err := config.GetConfigFilePath()
+	if err != nil {
+		return false
+	}
+	names, ok := cache.LoadProjectList(configFile, listOptions(), listCacheTTL)
+	if !ok {
+		return false
+	}
+	for _, name := range names {
+		fmt.Println(name)
+	}
+	return true
+}
+
+// listOptions describes the command line options that change what plain
+// sesh list prints, so a list made with others isn't reused
+func listOptions() string {
+	return fmt.Sprintf("profile=%s no-zoxide=%t", config.ActiveProfile(), noZoxide)
+}
+
+// listProjectsLong prints each project with the size of its working tree,
+// to help spot large repos worth archiving
+func listProjectsLong(projects []finder.Project) error {
 	for _, p := range projects {
-		fmt.Println(p.Name)
+		size, err := preview.LoadSize(p.Path)
+		if err != nil {
+			fmt.Printf("%-30s %10s %12s  %s\n", p.Name, "-", "-", config.ContractPath(p.Path))
+			continue
+		}
+		fmt.Printf("%-30s %10s %6d files  %s\n", p.Name, preview.FormatBytes(size.Bytes), size.Files, config.ContractPath(p.Path))
 	}
 	return nil
 }
 
-func listTmuxSessions() error {
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}")
-	output, err := cmd.Output()
+func listTmuxSessions(showClients bool) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	if showClients {
+		// One line per session: its name, then the ttys of attached clients
+		sessions, err := tmux.ListSessionInfo(context.Background())
+		if err != nil {
+			// tmux not running or no sessions
+			return nil
+		}
+		for _, s := range sessions {
+			fmt.Printf("%s\t%s\n", s.Name, strings.Join(s.Clients, ","))
+		}
+		return nil
+	}
+
+	sessions, err := tmux.ListSessions()
 	if err != nil {
 		// tmux not running or no sessions
 		return nil
 	}
-
-	sessions := strings.TrimSpace(string(output))
-	if sessions != "" {
-		fmt.Println(sessions)
+	for _, s := range sessions {
+		fmt.Println(s)
 	}
 	return nil
 }
 
+// parseConnectFlags consumes the flags for opening sessions, --window
+// (which window to land on) and --group (open a grouped session when the
+// session is in use), and returns the remaining arguments
+func parseConnectFlags(args []string) ([]string, error) {
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--window" || args[i] == "-w":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s requires a window name", args[i])
+			}
+			i++
+			tmux.SetWindow(args[i])
+		case strings.HasPrefix(args[i], "--window="):
+			tmux.SetWindow(strings.TrimPrefix(args[i], "--window="))
+		case args[i] == "--group" || args[i] == "-g":
+			tmux.SetMultiClient("group")
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return rest, nil
+}
+
 func runConnect(name string) error {
-	cfg, err := config.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
 		return err
 	}
 
-	projects, err := finder.FindGitProjects(cfg.ProjectDirectories)
+	// project:window opens the project on that window, as --window does
+	if i := strings.LastIndex(name, ":"); i > 0 && i < len(name)-1 {
+		tmux.SetWindow(name[i+1:])
+		name = name[:i]
+	}
+	if isPathArg(name) {
+		return connectPath(name)
+	}
+
+	// A project with exactly the name given is opened as soon as a scan finds
+	// it, the daemon's if it is running, rather than once the scan is done
+	exact, err := daemon.FindProject(name)
+	var projects []finder.Project
+	if err == nil && exact == nil {
+		if projects, err = daemon.FetchProjects(); err == nil {
+			finder.Sort(projects, cfg.Sort)
+		}
+	}
 	if err != nil {
-		return err
+		projects, exact, err = finder.FindGitProjectsUntil(context.Background(), cfg.ProjectDirectories, cfg.Projects, cfg.Sort, func(p finder.Project) bool {
+			return p.Named(name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if exact != nil {
+		return openProject(*exact)
+	}
+
+	// Weak matches (a short prefix, or letters scattered through a name) are
+	// rejected below connect_min_score in favour of suggestions
+	candidate, ok := matchProject(projects, name)
+	if !ok {
+		candidate, ok = fuzzyMatchProject(projects, name)
+	}
+	if ok && (candidate.Named(name) || matchScore(name, candidate.Name) >= cfg.ConnectMinScore) {
+		return openProject(candidate)
+	}
+
+	return offerSuggestions(projects, name)
+}
+
+// fuzzyMatchProject returns the best fuzzy match for name, as ranked by the
+// picker's matcher
+func fuzzyMatchProject(projects []finder.Project, name string) (finder.Project, bool) {
+	matches := fuzzy.FindFrom(name, projectNames(projects))
+	if len(matches) == 0 {
+		return finder.Project{}, false
+	}
+	return projects[matches[0].Index], true
+}
+
+// matchScore rates how well a typed name identifies a project between 0 and
+// 1: the share of the project name covered by what was typed. Exact and
+// session name matches score 1.
+func matchScore(typed, projectName string) float64 {
+	if strings.EqualFold(typed, projectName) || tmux.SanitizeSessionName(typed) == tmux.SanitizeSessionName(projectName) {
+		return 1
+	}
+	n := utf8.RuneCountInString(projectName)
+	if n == 0 {
+		return 0
 	}
+	return min(1, float64(utf8.RuneCountInString(typed))/float64(n))
+}
+
+// projectNames adapts a project list for fuzzy.FindFrom
+type projectNames []finder.Project
+
+func (p projectNames) String(i int) string { return p[i].Name }
+func (p projectNames) Len() int            { return len(p) }
 
-	// First, try exact match (case-insensitive)
+// matchProject resolves a user supplied name to a project: an exact
+// (case-insensitive) name or short code match first, then a session name
+// match, then a prefix match
+func matchProject(projects []finder.Project, name string) (finder.Project, bool) {
 	nameLower := strings.ToLower(name)
 	for _, p := range projects {
-		if strings.ToLower(p.Name) == nameLower {
-			// Record in recent history and zoxide
-			recent, _ := cache.Load()
-			if recent != nil {
-				recent.Add(p.Name, p.Path)
-				_ = recent.Save()
-			}
-			_ = zoxide.Add(p.Path) // Track in zoxide for frecency
-			return tmux.GetOrCreateSession(p)
+		if p.Named(name) {
+			return p, true
 		}
 	}
 
 	// Try sanitized session name match
 	sanitizedName := tmux.SanitizeSessionName(name)
 	for _, p := range projects {
-		if tmux.SanitizeSessionName(p.Name) == sanitizedName {
-			recent, _ := cache.Load()
-			if recent != nil {
-				recent.Add(p.Name, p.Path)
-				_ = recent.Save()
-			}
-			_ = zoxide.Add(p.Path) // Track in zoxide for frecency
-			return tmux.GetOrCreateSession(p)
+		if tmux.SanitizeSessionName(p.Name) == sanitizedName || tmux.SessionName(p) == sanitizedName {
+			return p, true
 		}
 	}
 
 	// Try prefix match as fallback
 	for _, p := range projects {
 		if strings.HasPrefix(strings.ToLower(p.Name), nameLower) {
-			recent, _ := cache.Load()
-			if recent != nil {
-				recent.Add(p.Name, p.Path)
-				_ = recent.Save()
-			}
-			_ = zoxide.Add(p.Path) // Track in zoxide for frecency
-			return tmux.GetOrCreateSession(p)
+			return p, true
 		}
 	}
 
-	return fmt.Errorf("project not found: %s\n\nAvailable projects:\n%s",
-		name, getProjectList(projects))
+	return finder.Project{}, false
 }
 
-func getProjectList(projects []finder.Project) string {
-	var names []string
-	for _, p := range projects {
-		names = append(names, "  - "+p.Name)
+// sessionsUnder returns the sessions whose directory is root or below it
+func sessionsUnder(infos []tmux.SessionInfo, root string) ([]tmux.SessionInfo, error) {
+	dir, err := filepath.Abs(config.ExpandPath(root))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --root %s: %w", root, err)
 	}
-	return strings.Join(names, "\n")
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+
+	var under []tmux.SessionInfo
+	for _, info := range infos {
+		path := info.Path
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			under = append(under, info)
+		}
+	}
+	if len(under) == 0 {
+		return nil, fmt.Errorf("no tmux sessions under %s", config.ContractPath(dir))
+	}
+	return under, nil
 }
 
-func runSwitch() error {
-	// Get active tmux sessions
-	cmd := exec.Command("tmux", "list-sessions", "-F", "#{session_name}:#{session_path}")
-	output, err := cmd.Output()
+func runSwitch(args []string) error {
+	// Parse flags
+	client, root := "", ""
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--client" || args[i] == "-c":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--client requires a tty")
+			}
+			i++
+			client = args[i]
+		case strings.HasPrefix(args[i], "--client="):
+			client = strings.TrimPrefix(args[i], "--client=")
+		case args[i] == "--root":
+			if i+1 >= len(args) {
+				return fmt.Errorf("--root requires a directory")
+			}
+			i++
+			root = args[i]
+		case strings.HasPrefix(args[i], "--root="):
+			root = strings.TrimPrefix(args[i], "--root=")
+		default:
+			names = append(names, args[i])
+		}
+	}
+
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("no active tmux sessions")
+		return err
 	}
 
-	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-	if len(lines) == 0 || (len(lines) == 1 && lines[0] == "") {
+	if client != "" {
+		// Accept ttys as shown by tmux (/dev/pts/3) or without /dev/ (pts/3)
+		if !strings.HasPrefix(client, "/dev/") {
+			client = "/dev/" + client
+		}
+		if !tmux.ClientExists(client) {
+			return fmt.Errorf("no tmux client attached on %s", client)
+		}
+	}
+
+	// Get active tmux sessions
+	infos, err := tmux.ListSessionInfo(context.Background())
+	if err != nil || len(infos) == 0 {
 		return fmt.Errorf("no active tmux sessions")
 	}
+	if root != "" {
+		if infos, err = sessionsUnder(infos, root); err != nil {
+			return err
+		}
+	}
+
+	// A session given by name skips the picker
+	if len(names) > 0 {
+		name := strings.Join(names, " ")
+		for _, info := range infos {
+			if info.Name == name {
+				return switchTo(name, client)
+			}
+		}
+		return fmt.Errorf("no tmux session named %s", name)
+	}
 
 	// Convert to Project structs for the UI
 	var sessions []finder.Project
-	for _, line := range lines {
-		parts := strings.SplitN(line, ":", 2)
-		name := parts[0]
-		path := ""
-		if len(parts) > 1 {
-			path = parts[1]
+	for _, info := range infos {
+		detail := ""
+		if len(info.Clients) > 0 {
+			detail = "attached: " + strings.Join(info.Clients, ", ")
 		}
 		sessions = append(sessions, finder.Project{
-			Name: name,
-			Path: path,
+			Name:   info.Name,
+			Path:   info.Path,
+			Detail: detail,
 		})
 	}
 
+	current := tmux.CurrentSession()
+	kill := func(session finder.Project) error {
+		// Killing the session we run in would take the picker down with it
+		if session.Name == current {
+			return fmt.Errorf("not killing the current session %s", session.Name)
+		}
+		return tmux.KillSession(session.Name, cfg.SnapshotOnKill)
+	}
+
+	rename := func(session finder.Project, name string) (string, error) {
+		return tmux.RenameSession(session.Name, name)
+	}
+
 	// Display session selector UI
-	selectedSession, err := ui.SelectProject(sessions)
+	selectedSession, err := ui.SelectProjectWithOptions(sessions, ui.Options{Kill: kill, Rename: rename})
 	if err != nil {
 		return fmt.Errorf("failed to select session: %w", err)
 	}
@@ -239,53 +791,102 @@ func runSwitch() error {
 		return nil
 	}
 
-	// Switch to selected session
-	return tmux.SwitchSession(selectedSession.Name)
+	return switchTo(selectedSession.Name, client)
+}
+
+// switchTo switches the given client, or the current one when empty, to a session
+func switchTo(sessionName, client string) error {
+	if client == "" {
+		return tmux.SwitchSession(sessionName)
+	}
+	return tmux.SwitchClient(sessionName, client)
 }
 
 func runInteractive() error {
+	selectedProject, err := pickProject()
+	if err != nil {
+		return err
+	}
+
+	// If user quit without selecting, exit gracefully
+	if selectedProject == nil {
+		return nil
+	}
+
+	// Create or attach to tmux session
+	if err := openProject(*selectedProject); err != nil {
+		return fmt.Errorf("failed to manage tmux session: %w", err)
+	}
+
+	return nil
+}
+
+// pickProject shows the project picker and returns the chosen project, or
+// nil if the user quit without choosing
+func pickProject() (*finder.Project, error) {
 	// Load configuration
-	cfg, err := config.LoadConfig()
+	cfg, err := loadConfig()
 	if err != nil {
-		return fmt.Errorf("failed to load config: %w", err)
+		return nil, fmt.Errorf("failed to load config: %w", err)
 	}
 
 	// Find all Git projects
-	projects, err := finder.FindGitProjects(cfg.ProjectDirectories)
+	projects, err := finder.FindGitProjects(context.Background(), cfg.ProjectDirectories, cfg.Projects, cfg.Sort)
 	if err != nil {
-		return fmt.Errorf("failed to find projects: %w", err)
+		return nil, fmt.Errorf("failed to find projects: %w", err)
 	}
 
 	// If no projects found, show helpful message
 	if len(projects) == 0 {
 		configPath, _ := config.GetConfigFilePath()
-		return fmt.Errorf("no Git projects found in configured directories.\n\nConfigured directories:\n%v\n\nEdit your config at: %s",
-			cfg.ProjectDirectories, configPath)
+		return nil, fmt.Errorf("no Git projects found in configured directories.\n\nConfigured directories:\n%v\n\nEdit your config at: %s",
+			cfg.DirectoryPaths(), configPath)
 	}
 
-	// Display project selector UI
-	selectedProject, err := ui.SelectProject(projects)
-	if err != nil {
-		return fmt.Errorf("failed to select project: %w", err)
+	// Display project selector UI, with ctrl+r picking up config edits. The
+	// picker applies the reloaded config itself, as it reads the settings
+	// that change.
+	reload := func(ctx context.Context) ([]finder.Project, *config.Config, error) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return nil, nil, err
+		}
+		projects, err := finder.FindGitProjects(ctx, cfg.ProjectDirectories, cfg.Projects, cfg.Sort)
+		return projects, cfg, err
 	}
-
-	// If user quit without selecting, exit gracefully
-	if selectedProject == nil {
-		return nil
+	selectedProject, err := ui.SelectProjectWithOptions(projects, ui.Options{Reload: reload, Configure: applyConfig, Sort: cfg.Sort})
+	if err != nil {
+		return nil, fmt.Errorf("failed to select project: %w", err)
 	}
+	return selectedProject, nil
+}
 
-	// Record the selected project in recent history and zoxide
+// openProject records the project in recent history and zoxide, then
+// creates or attaches to its tmux session
+func openProject(p finder.Project) error {
 	recent, _ := cache.Load()
 	if recent != nil {
-		recent.Add(selectedProject.Name, selectedProject.Path)
+		recent.Add(p.Name, p.Path)
 		_ = recent.Save() // Ignore errors for cache saves
 	}
-	_ = zoxide.Add(selectedProject.Path) // Track in zoxide for frecency
-
-	// Create or attach to tmux session
-	if err := tmux.GetOrCreateSession(*selectedProject); err != nil {
-		return fmt.Errorf("failed to manage tmux session: %w", err)
+	_ = cache.RecordOpen(p.Path)
+	if _, _, remote := config.ParseRemote(p.Path); !remote {
+		_ = zoxide.Add(p.Path) // Track in zoxide for frecency
 	}
+	// The project moves up the frecency order, so list it again
+	_ = cache.ClearProjectList()
 
-	return nil
+	slog.Info("opening project", "name", p.Name, "path", p.Path)
+	return tmux.GetOrCreateSession(p)
+}
+
+// isTerminal reports whether stdin and stdout are both attached to a terminal
+func isTerminal() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
 }
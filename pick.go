@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+)
+
+// runPick shows the project picker. With --print the choice is written to
+// stdout for scripts instead of being opened in tmux.
+func runPick(args []string) error {
+	printOnly, printName := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--print":
+			printOnly = true
+		case "--name":
+			printName = true
+		default:
+			return fmt.Errorf("unknown flag %s\n\nusage: sesh pick [--print [--name]]", arg)
+		}
+	}
+	if printName && !printOnly {
+		return fmt.Errorf("--name requires --print")
+	}
+
+	if !printOnly {
+		return runInteractive()
+	}
+
+	selected, err := pickProject()
+	if err != nil {
+		return err
+	}
+	if selected == nil {
+		// Fail so scripts can tell a cancelled pick from a choice
+		return fmt.Errorf("no project selected")
+	}
+
+	if printName {
+		fmt.Println(selected.Name)
+	} else {
+		fmt.Println(selected.Path)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// runPrune kills sessions whose directory is gone and drops deleted
+// projects from sesh's caches and zoxide. With --dry-run it only lists them.
+func runPrune(args []string) error {
+	dryRun := false
+	for _, arg := range args {
+		switch arg {
+		case "-n", "--dry-run":
+			dryRun = true
+		default:
+			return fmt.Errorf("usage: sesh prune [--dry-run]")
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	sessions := deadSessions()
+	ghosts := findGhostPaths()
+	if len(sessions) == 0 && len(ghosts) == 0 {
+		fmt.Println("Nothing to prune")
+		return nil
+	}
+
+	verb := "Killed"
+	if dryRun {
+		verb = "Would kill"
+	}
+	for _, s := range sessions {
+		if !dryRun {
+			if err := tmux.KillSession(s.Name, cfg.SnapshotOnKill); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("%s session %s (%s no longer exists)\n", verb, s.Name, config.ContractPath(sessionDir(s)))
+	}
+
+	verb = "Removed"
+	if dryRun {
+		verb = "Would remove"
+	}
+	for _, path := range ghosts {
+		fmt.Printf("%s %s from the cache\n", verb, config.ContractPath(path))
+	}
+	if dryRun || len(ghosts) == 0 {
+		return nil
+	}
+	return pruneGhostPaths(ghosts)
+}
+
+// deadSessions returns the sessions whose directory no longer exists, the
+// current session last so it outlives the others while sesh runs in it
+func deadSessions() []tmux.SessionInfo {
+	sessions, err := tmux.ListSessionInfo(context.Background())
+	if err != nil {
+		// tmux not running
+		return nil
+	}
+
+	current := tmux.CurrentSession()
+	var dead []tmux.SessionInfo
+	var last *tmux.SessionInfo
+	for _, s := range sessions {
+		dir := sessionDir(s)
+		if _, _, remote := config.ParseRemote(dir); remote || dir == "" {
+			continue
+		}
+		if _, err := os.Stat(dir); !os.IsNotExist(err) {
+			continue
+		}
+		if s.Name == current {
+			last = &s
+			continue
+		}
+		dead = append(dead, s)
+	}
+	if last != nil {
+		dead = append(dead, *last)
+	}
+	return dead
+}
+
+// sessionDir returns the directory a session belongs to: its project for
+// sessions sesh created, else the directory tmux started it in
+func sessionDir(s tmux.SessionInfo) string {
+	if s.Project != "" {
+		return s.Project
+	}
+	return s.Path
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+func runRename(args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: sesh rename <old> <new>")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	sessionName, err := resolveSession(cfg, args[0])
+	if err != nil {
+		return err
+	}
+	name, err := tmux.RenameSession(sessionName, args[1])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Renamed session %s to %s\n", sessionName, name)
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/preview"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+func runRun(args []string) error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	if len(args) > 0 && (args[0] == "--list" || args[0] == "-l") {
+		return listJobs()
+	}
+
+	if len(args) < 3 || args[1] != "--" {
+		return fmt.Errorf("usage: sesh run <name> -- <command>\n       sesh run --list")
+	}
+
+	name := tmux.SanitizeSessionName(args[0])
+	if name == "" {
+		return fmt.Errorf("invalid job name: %s", args[0])
+	}
+
+	command := shellJoin(args[2:])
+	dir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+
+	if err := tmux.StartJob(name, dir, command); err != nil {
+		return err
+	}
+
+	jobs, _ := cache.LoadJobs()
+	jobs = append(jobs, cache.Job{Name: name, Command: command, Dir: dir, Started: time.Now()})
+	if err := cache.SaveJobs(jobs); err != nil {
+		return fmt.Errorf("failed to track job: %w", err)
+	}
+
+	fmt.Printf("Started job '%s' (attach with: tmux attach -t %s)\n", name, name)
+	return nil
+}
+
+// listJobs prints tracked jobs and their status, forgetting jobs whose
+// session has since been closed
+func listJobs() error {
+	jobs, err := cache.LoadJobs()
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No jobs")
+		return nil
+	}
+
+	var remaining []cache.Job
+	for _, job := range jobs {
+		state, code := tmux.JobStatus(job.Name)
+
+		var status string
+		switch state {
+		case tmux.JobRunning:
+			status = "running"
+			remaining = append(remaining, job)
+		case tmux.JobExited:
+			status = fmt.Sprintf("exited (%d)", code)
+			remaining = append(remaining, job)
+		case tmux.JobGone:
+			status = "closed"
+		}
+
+		fmt.Printf("%-20s %-12s %-10s %s\n", job.Name, status, preview.FormatAge(time.Since(job.Started)), job.Command)
+	}
+
+	return cache.SaveJobs(remaining)
+}
+
+// shellJoin joins command arguments into a single shell command line. A
+// single argument is used as-is so `sesh run x -- "make && make test"` works.
+func shellJoin(args []string) string {
+	if len(args) == 1 {
+		return args[0]
+	}
+
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		if arg != "" && !strings.ContainsAny(arg, " \t\n'\"\\$`!*?[]{}()<>|&;#~") {
+			quoted[i] = arg
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestShellJoin(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"make && make test"}, "make && make test"},
+		{[]string{"go", "test", "./..."}, "go test ./..."},
+		{[]string{"echo", "hello world"}, "echo 'hello world'"},
+		{[]string{"echo", "it's"}, `echo 'it'\''s'`},
+		{[]string{"echo", "$HOME"}, "echo '$HOME'"},
+		{[]string{"ls", "*.go"}, "ls '*.go'"},
+		{[]string{"printf", ""}, "printf ''"},
+		{[]string{"git", "log", "--format=%h"}, "git log --format=%h"},
+	}
+	for _, tt := range tests {
+		if got := shellJoin(tt.args); got != tt.want {
+			t.Errorf("shellJoin(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/daemon"
+	"github.com/adamflitney/sesh/internal/logging"
+)
+
+func runServe(args []string) error {
+	for _, arg := range args {
+		switch arg {
+		case "--stats":
+			return printServeStats()
+		case "--reload":
+			return reloadServe()
+		}
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	// Scans keep running through a reload, so it leaves alone the settings
+	// they read, handing the daemon the new config instead
+	reload := func() (*config.Config, error) {
+		cfg, err := config.LoadConfig()
+		if err != nil {
+			return nil, err
+		}
+		if err := logging.Init(cfg.LogLevel); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+	return daemon.NewServer(cfg, reload).Run()
+}
+
+// reloadServe has a running daemon pick up config changes
+func reloadServe() error {
+	if !daemon.IsRunning() {
+		return fmt.Errorf("sesh serve is not running")
+	}
+	if err := daemon.Reload(); err != nil {
+		return err
+	}
+	fmt.Println("Reloaded the sesh serve config")
+	return nil
+}
+
+// printServeStats prints a readable summary of a running daemon's metrics
+func printServeStats() error {
+	m, err := daemon.FetchMetrics()
+	if err != nil {
+		return fmt.Errorf("sesh serve is not running")
+	}
+
+	hits, misses := m["sesh_cache_hits_total"], m["sesh_cache_misses_total"]
+	hitRate := 0.0
+	if hits+misses > 0 {
+		hitRate = hits / (hits + misses) * 100
+	}
+
+	avgScan := 0.0
+	if m["sesh_scans_total"] > 0 {
+		avgScan = m["sesh_scan_duration_seconds_sum"] / m["sesh_scans_total"]
+	}
+
+	fmt.Printf("Uptime:         %s\n", time.Duration(m["sesh_uptime_seconds"])*time.Second)
+	fmt.Printf("Scans:          %.0f (last %s, avg %s)\n", m["sesh_scans_total"],
+		secondsToDuration(m["sesh_scan_duration_seconds_last"]), secondsToDuration(avgScan))
+	fmt.Printf("Project lists:  %.0f requests\n", m[`sesh_requests_total{path="/projects"}`])
+	fmt.Printf("Cache hit rate: %.0f%% (%.0f hits, %.0f misses)\n", hitRate, hits, misses)
+	fmt.Printf("Shared scans:   %.0f requests waited for a scan already running\n", m["sesh_scans_coalesced_total"])
+	return nil
+}
+
+// secondsToDuration converts fractional seconds to a rounded duration
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second)).Round(time.Microsecond)
+}
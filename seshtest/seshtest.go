@@ -0,0 +1,151 @@
+// Package seshtest runs tmux servers for integration tests of sesh's layouts,
+// hooks and backends.
+//
+// A Server points tmux (through TMUX_TMPDIR) and sesh's config, cache and
+// state directories (through the XDG variables) at temporary directories
+// for the rest of the test, so nothing touches the user's own tmux server
+// or files. Tests in the sesh module drive sessions through its packages,
+// and any test can run sesh's binary or tmux itself against the server:
+//
+//	func TestLayout(t *testing.T) {
+//		srv := seshtest.NewServer(t)
+//		srv.Run("new-session", "-d", "-s", "api", "-n", "editor")
+//		srv.Run("new-window", "-t", "=api:", "-n", "shell")
+//		if got := srv.Windows("api"); !slices.Equal(got, []string{"editor", "shell"}) {
+//			t.Errorf("windows = %v", got)
+//		}
+//	}
+//
+// As it changes the environment, tests using a Server can't run in parallel.
+package seshtest
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// idleSession keeps the server running while the test has no sessions of its
+// own. It is left out of Sessions.
+const idleSession = "seshtest"
+
+// Server is a tmux server private to one test
+type Server struct {
+	t   testing.TB
+	Dir string // Temporary directory holding the socket and sesh's files
+}
+
+// NewServer starts a tmux server for the test, skipping the test when tmux
+// isn't installed. The server is killed when the test ends.
+func NewServer(t testing.TB) *Server {
+	t.Helper()
+	if _, err := exec.LookPath("tmux"); err != nil {
+		t.Skip("tmux is not installed")
+	}
+
+	s := &Server{t: t, Dir: t.TempDir()}
+	t.Setenv("TMUX_TMPDIR", s.Dir)
+	// Outside tmux as far as sesh and tmux are concerned
+	t.Setenv("TMUX", "")
+	t.Setenv("SESH_TARGET_CLIENT", "")
+	for _, env := range []string{"XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_STATE_HOME"} {
+		dir := filepath.Join(s.Dir, strings.ToLower(strings.TrimPrefix(env, "XDG_")))
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatalf("seshtest: %v", err)
+		}
+		t.Setenv(env, dir)
+	}
+
+	// Start without the user's tmux.conf so tests see tmux's defaults
+	s.Run("-f", os.DevNull, "new-session", "-d", "-s", idleSession)
+	t.Cleanup(func() {
+		_ = exec.Command("tmux", "kill-server").Run()
+	})
+	return s
+}
+
+// Run runs a tmux command against the server and returns its trimmed output,
+// failing the test if it fails
+func (s *Server) Run(args ...string) string {
+	s.t.Helper()
+	output, err := exec.Command("tmux", args...).CombinedOutput()
+	if err != nil {
+		s.t.Fatalf("seshtest: tmux %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// lines runs a tmux command and splits its output into lines
+func (s *Server) lines(args ...string) []string {
+	s.t.Helper()
+	output := s.Run(args...)
+	if output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
+
+// Sessions returns the names of the sessions on the server
+func (s *Server) Sessions() []string {
+	s.t.Helper()
+	var sessions []string
+	for _, name := range s.lines("list-sessions", "-F", "#{session_name}") {
+		if name != idleSession {
+			sessions = append(sessions, name)
+		}
+	}
+	return sessions
+}
+
+// HasSession reports whether a session of the given name exists
+func (s *Server) HasSession(name string) bool {
+	return exec.Command("tmux", "has-session", "-t", "="+name).Run() == nil
+}
+
+// Windows returns the names of a session's windows in order
+func (s *Server) Windows(session string) []string {
+	s.t.Helper()
+	return s.lines("list-windows", "-t", "="+session, "-F", "#{window_name}")
+}
+
+// Option returns a session option, such as @sesh_project
+func (s *Server) Option(session, name string) string {
+	s.t.Helper()
+	return s.Run("show-options", "-v", "-t", "="+session, name)
+}
+
+// Capture returns the visible contents of a pane, e.g. "api:editor"
+func (s *Server) Capture(target string) string {
+	s.t.Helper()
+	return s.Run("capture-pane", "-p", "-t", target)
+}
+
+// WaitFor polls cond until it holds, failing the test after timeout. Use it
+// for the output of commands started in panes, which run asynchronously.
+func (s *Server) WaitFor(timeout time.Duration, cond func() bool) {
+	s.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			s.t.Fatalf("seshtest: condition not met after %s", timeout)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// WriteConfig writes sesh's config file and returns its path. Load it with
+// config.LoadConfig and pass it to tmux.SetConfig.
+func (s *Server) WriteConfig(yaml string) string {
+	s.t.Helper()
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "sesh", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		s.t.Fatalf("seshtest: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(yaml), 0600); err != nil {
+		s.t.Fatalf("seshtest: %v", err)
+	}
+	return path
+}
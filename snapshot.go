@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+func runSnapshot(args []string) error {
+	usage := fmt.Errorf("usage: sesh snapshot save [file] [--auto] | sesh snapshot restore [file]")
+	var auto bool
+	var positional []string
+	for _, arg := range args {
+		switch arg {
+		case "--auto":
+			auto = true
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) < 1 || len(positional) > 2 || auto && (positional[0] != "save" || len(positional) != 1) {
+		return usage
+	}
+
+	path, err := cache.SnapshotPath()
+	if err != nil {
+		return err
+	}
+	if len(positional) == 2 {
+		path = config.ExpandPath(positional[1])
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	switch positional[0] {
+	case "save":
+		if auto {
+			// For tmux hooks: quiet, and only with auto_snapshot on
+			tmux.AutoSnapshot()
+			return nil
+		}
+		return saveSnapshot(path)
+	case "restore":
+		if err := restoreSnapshot(path); err != nil {
+			return err
+		}
+		if defaultPath, _ := cache.SnapshotPath(); cfg.AutoSnapshot && path == defaultPath {
+			// Automatic snapshots pick up from the restored sessions
+			_, err = tmux.SaveSnapshot(path)
+		}
+		return err
+	default:
+		return usage
+	}
+}
+
+// runRestore restores the default snapshot. With --auto, meant for tmux to
+// run as its server starts, it does so only with auto_snapshot on and only
+// once per server, quietly.
+func runRestore(args []string) error {
+	switch {
+	case len(args) == 0:
+		return runSnapshot([]string{"restore"})
+	case len(args) == 1 && args[0] == "--auto":
+	default:
+		return fmt.Errorf("usage: sesh restore [--auto]")
+	}
+
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	restored, err := tmux.AutoRestore()
+	if len(restored) > 0 {
+		slog.Info("restored sessions at server start", "sessions", restored)
+	}
+	return err
+}
+
+// saveSnapshot records the live sesh sessions to path
+func saveSnapshot(path string) error {
+	snap, err := tmux.SaveSnapshot(path)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d sessions to %s\n", len(snap.Sessions), config.ContractPath(path))
+	return nil
+}
+
+// restoreSnapshot recreates the sessions recorded at path that aren't running
+func restoreSnapshot(path string) error {
+	snap, err := cache.LoadSnapshot(path)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no snapshot at %s, save one with sesh snapshot save", config.ContractPath(path))
+	}
+	if err != nil {
+		return err
+	}
+
+	restored, err := tmux.RestoreSnapshot(snap)
+	for _, name := range restored {
+		fmt.Printf("Restored %s\n", name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(restored) == 0 {
+		fmt.Println("Every session in the snapshot is already running")
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/ssh"
+	"github.com/adamflitney/sesh/internal/tmux"
+	"github.com/adamflitney/sesh/internal/ui"
+)
+
+func runSSH() error {
+	hosts, err := ssh.LoadHosts()
+	if err != nil {
+		return fmt.Errorf("failed to read ssh config: %w", err)
+	}
+	if len(hosts) == 0 {
+		return fmt.Errorf("no hosts found in ~/.ssh/config")
+	}
+
+	// Present hosts through the project picker, showing the address as the path
+	choices := make([]finder.Project, 0, len(hosts))
+	for _, h := range hosts {
+		choices = append(choices, finder.Project{Name: h.Name, Path: h.Address()})
+	}
+
+	selected, err := ui.SelectProject(choices)
+	if err != nil {
+		return fmt.Errorf("failed to select host: %w", err)
+	}
+	if selected == nil {
+		return nil
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return err
+	}
+
+	// One session per host, starting with a window connected to it
+	session := finder.Project{Name: "ssh-" + selected.Name, Path: home, Session: "ssh-" + selected.Name}
+	layout := tmux.Layout{Windows: []tmux.Window{
+		{Name: "ssh", Command: "ssh " + selected.Name},
+		{Name: "local"},
+	}}
+	return tmux.OpenSession(session, layout)
+}
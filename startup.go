@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// runCheckStartup reports the windows of a session whose startup command
+// failed: on the terminal when run by hand, otherwise (as when the tmux
+// server runs it after creating the session) on the session's clients
+func runCheckStartup(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: sesh check-startup <session>")
+	}
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	failures, err := tmux.StartupFailures(args[0])
+	if err != nil {
+		return err
+	}
+	if !isTerminal() {
+		if len(failures) == 0 {
+			return nil
+		}
+		return tmux.ReportStartupFailures(args[0], failures)
+	}
+
+	if len(failures) == 0 {
+		fmt.Println("No failed startup commands")
+		return nil
+	}
+	for _, f := range failures {
+		fmt.Printf("%s: %s\n  %s\n", f.Window, f.Command, f.Error)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/daemon"
+	"github.com/adamflitney/sesh/internal/preview"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+func runStatus() error {
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	sessions, err := tmux.ListSessionInfo(context.Background())
+	if err != nil {
+		// tmux not running or no sessions
+		sessions = nil
+	}
+
+	current := tmux.CurrentSession()
+	if current != "" {
+		fmt.Printf("Current session: %s\n", current)
+	} else {
+		fmt.Println("Current session: (not inside tmux)")
+	}
+	fmt.Println()
+
+	fmt.Printf("Active sessions (%d):\n", len(sessions))
+	if len(sessions) == 0 {
+		fmt.Println("  none")
+	}
+
+	for _, s := range sessions {
+		marker := " "
+		if s.Name == current {
+			marker = "*"
+		}
+
+		project := "(not created by sesh)"
+		if s.Project != "" {
+			project = config.ContractPath(s.Project)
+		}
+
+		clients := "detached"
+		if len(s.Clients) > 0 {
+			clients = "attached: " + strings.Join(s.Clients, ", ")
+		}
+
+		fmt.Printf(" %s %-24s %-40s %s\n", marker, s.Name, project, clients)
+	}
+	fmt.Println()
+
+	if m, err := daemon.FetchMetrics(); err == nil {
+		fmt.Printf("Daemon: running, %.0f scans", m["sesh_scans_total"])
+		if last, ok := m["sesh_scan_last_timestamp_seconds"]; ok {
+			fmt.Printf(", last %s", preview.FormatAge(time.Since(time.Unix(int64(last), 0))))
+		}
+		fmt.Println()
+	} else {
+		fmt.Println("Daemon: not running")
+	}
+
+	modified, err := cache.LastModified()
+	if err != nil {
+		fmt.Println("Recent cache: empty")
+	} else {
+		fmt.Printf("Recent cache: updated %s\n", preview.FormatAge(time.Since(modified)))
+	}
+
+	return nil
+}
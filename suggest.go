@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/ui"
+	"github.com/sahilm/fuzzy"
+)
+
+// maxSuggestions is how many similar names are offered when connect fails
+const maxSuggestions = 5
+
+// offerSuggestions handles a name that matched no project: on a terminal the
+// closest projects are offered in the picker, otherwise they're listed in
+// the error
+func offerSuggestions(projects []finder.Project, name string) error {
+	suggestions := suggestProjects(projects, name, maxSuggestions)
+	if len(suggestions) == 0 {
+		return fmt.Errorf("project not found: %s\n\nRun 'sesh list' to see all projects", name)
+	}
+
+	if !isTerminal() {
+		names := make([]string, len(suggestions))
+		for i, p := range suggestions {
+			names[i] = p.Name
+		}
+		return fmt.Errorf("project not found: %s\n\nDid you mean: %s?", name, strings.Join(names, ", "))
+	}
+
+	fmt.Printf("No project matches %s, pick one of the closest:\n", name)
+	selected, err := ui.SelectProject(suggestions)
+	if err != nil {
+		return fmt.Errorf("failed to select project: %w", err)
+	}
+	if selected == nil {
+		return fmt.Errorf("project not found: %s", name)
+	}
+	return openProject(*selected)
+}
+
+// suggestProjects returns up to n projects whose names are closest to name:
+// fuzzy matches first, as they contain every typed letter, then names within
+// a few typos ranked by edit distance
+func suggestProjects(projects []finder.Project, name string, n int) []finder.Project {
+	var suggestions []finder.Project
+	seen := make(map[string]bool)
+	for _, m := range fuzzy.FindFrom(name, projectNames(projects)) {
+		suggestions = append(suggestions, projects[m.Index])
+		seen[projects[m.Index].Path] = true
+	}
+
+	type candidate struct {
+		project  finder.Project
+		distance int
+	}
+	var close []candidate
+	typed := strings.ToLower(name)
+	// Allow roughly one typo per three letters, so short names don't match everything
+	maxDistance := max(1, len([]rune(typed))/3)
+	for _, p := range projects {
+		if seen[p.Path] {
+			continue
+		}
+		if d := editDistance(typed, strings.ToLower(p.Name)); d <= maxDistance {
+			close = append(close, candidate{p, d})
+		}
+	}
+	sort.SliceStable(close, func(i, j int) bool { return close[i].distance < close[j].distance })
+	for _, c := range close {
+		suggestions = append(suggestions, c.project)
+	}
+
+	if len(suggestions) > n {
+		suggestions = suggestions[:n]
+	}
+	return suggestions
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b: Levenshtein distance that also counts swapping two adjacent letters,
+// the most common typo, as a single edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/config"
+	"github.com/adamflitney/sesh/internal/xdg"
+)
+
+func runTemplates(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: sesh templates sync [git-url] | list")
+	}
+
+	switch args[0] {
+	case "sync":
+		if len(args) > 2 {
+			return fmt.Errorf("usage: sesh templates sync [git-url]")
+		}
+		return syncTemplates(args[1:])
+	case "list":
+		return listTemplates()
+	default:
+		return fmt.Errorf("unknown templates command: %s", args[0])
+	}
+}
+
+// syncTemplates clones the repository at the given URL into the shared
+// templates directory, or pulls it if it's already there. Without a URL it
+// pulls every repository synced before.
+func syncTemplates(args []string) error {
+	dir, err := config.SharedTemplatesDir()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, xdg.DirMode()); err != nil {
+		return fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+
+	var repos []string
+	if len(args) == 1 {
+		name := repoName(args[0])
+		if name == "" {
+			return fmt.Errorf("can't tell the repository name from %s", args[0])
+		}
+		dest := filepath.Join(dir, name)
+		if _, err := os.Stat(dest); os.IsNotExist(err) {
+			if err := git("", "clone", args[0], dest); err != nil {
+				return fmt.Errorf("failed to clone %s: %w", args[0], err)
+			}
+		} else {
+			repos = append(repos, name)
+		}
+	} else {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if _, err := os.Stat(filepath.Join(dir, e.Name(), ".git")); err == nil {
+				repos = append(repos, e.Name())
+			}
+		}
+		if len(repos) == 0 {
+			return fmt.Errorf("no template repositories synced yet, run 'sesh templates sync <git-url>'")
+		}
+	}
+
+	for _, name := range repos {
+		if err := git(filepath.Join(dir, name), "pull", "--ff-only"); err != nil {
+			return fmt.Errorf("failed to update %s: %w", name, err)
+		}
+	}
+
+	// Loading the config checks the new templates
+	return listTemplates()
+}
+
+// git runs a git command in dir, showing its progress and errors
+func git(dir string, args ...string) error {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+// repoName returns the directory name for a git URL, e.g. layouts for
+// git@github.com:team/layouts.git
+func repoName(url string) string {
+	url = strings.TrimRight(strings.TrimSuffix(strings.TrimRight(url, "/"), ".git"), "/")
+	if i := strings.LastIndexAny(url, "/:"); i >= 0 {
+		url = url[i+1:]
+	}
+	if url == "." || url == ".." {
+		return ""
+	}
+	return url
+}
+
+// listTemplates prints every session template and where it comes from
+func listTemplates() error {
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		return err
+	}
+	if len(cfg.Templates) == 0 {
+		fmt.Println("No templates")
+		return nil
+	}
+
+	names := make([]string, 0, len(cfg.Templates))
+	for name := range cfg.Templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		source := cfg.Templates[name].Source
+		if source == "" {
+			source = "config"
+		}
+		fmt.Printf("%-24s %s\n", name, source)
+	}
+	return nil
+}
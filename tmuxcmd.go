@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// runTmux forwards args to tmux on the server sesh talks to, so scripts can
+// reach the same sessions. tmux reports its own errors, so a failing command
+// only passes its exit status on.
+func runTmux(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: sesh tmux <tmux arguments>")
+	}
+	// For the tmux_socket setting
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+
+	err := tmux.Command(args...).Run()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		os.Exit(exitErr.ExitCode())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run tmux: %w", err)
+	}
+	return nil
+}
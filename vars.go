@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// parseVars takes --var name=value flags out of args and passes the values
+// on to the layout
+func parseVars(args []string) ([]string, error) {
+	values := make(map[string]string)
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		var pair string
+		switch {
+		case args[i] == "--var":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("--var requires name=value")
+			}
+			i++
+			pair = args[i]
+		case strings.HasPrefix(args[i], "--var="):
+			pair = strings.TrimPrefix(args[i], "--var=")
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --var %q: expected name=value", pair)
+		}
+		values[name] = value
+	}
+
+	tmux.SetVars(values)
+	return rest, nil
+}
+
+// promptVar asks for the value of a layout variable on the terminal
+func promptVar(name, def string) (string, error) {
+	if def != "" {
+		fmt.Fprintf(os.Stderr, "%s [%s]: ", name, def)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s: ", name)
+	}
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("no value given for {{%s}}", name)
+	}
+	return strings.TrimSpace(answer), nil
+}
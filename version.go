@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Build metadata, set by packagers with
+//
+//	go build -ldflags "-X main.version=v0.3.0 -X main.commit=$(git rev-parse HEAD) -X main.date=$(date -u +%FT%TZ)"
+//
+// Builds without it fall back to what the Go toolchain recorded.
+var (
+	version string
+	commit  string
+	date    string
+)
+
+// pseudoVersion matches the timestamp and commit the Go toolchain puts in
+// versions of untagged builds, e.g. v0.0.0-20261015103259-bd4122404b8e
+var pseudoVersion = regexp.MustCompile(`\d{14}-[0-9a-f]{12}`)
+
+// versionInfo describes the running binary
+type versionInfo struct {
+	Version  string `json:"version"`
+	Commit   string `json:"commit,omitempty"`
+	Date     string `json:"date,omitempty"`
+	Dev      bool   `json:"dev"`                // Not a release build
+	Modified bool   `json:"modified,omitempty"` // Built from a tree with uncommitted changes
+	Go       string `json:"go"`
+	Platform string `json:"platform"`
+}
+
+// buildVersion collects the version metadata, taking what ldflags didn't
+// set from the build info: the module version for go install pkg@version,
+// and the commit for builds from a git checkout
+func buildVersion() versionInfo {
+	info := versionInfo{
+		Version:  version,
+		Commit:   commit,
+		Date:     date,
+		Go:       runtime.Version(),
+		Platform: runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+			info.Dev = pseudoVersion.MatchString(info.Version) || strings.HasSuffix(info.Version, "+dirty")
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+		info.Dev = true
+	}
+	return info
+}
+
+func runVersion(args []string) error {
+	info := buildVersion()
+	for _, arg := range args {
+		if arg == "--json" {
+			data, err := json.MarshalIndent(info, "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		}
+	}
+
+	var details []string
+	if info.Dev && info.Version != "dev" {
+		details = append(details, "dev build")
+	}
+	if info.Commit != "" {
+		details = append(details, shortCommit(info.Commit))
+	}
+	if info.Modified {
+		details = append(details, "modified")
+	}
+	if info.Date != "" {
+		details = append(details, "built "+info.Date)
+	}
+	details = append(details, info.Go, info.Platform)
+	fmt.Printf("sesh %s (%s)\n", info.Version, strings.Join(details, ", "))
+	return nil
+}
+
+// shortCommit abbreviates a commit hash the way git does by default
+func shortCommit(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
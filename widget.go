@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/adamflitney/sesh/internal/cache"
+	"github.com/adamflitney/sesh/internal/finder"
+	"github.com/adamflitney/sesh/internal/tmux"
+)
+
+// widgetRangePrefix starts the names of the status line ranges the recent
+// widget marks its projects with, followed by the project's position. tmux
+// limits range names to 15 characters, too few for project names.
+const widgetRangePrefix = "sesh-"
+
+func runWidget(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: sesh widget recent [--max N] [--current SESSION] | sesh widget click RANGE [CLIENT]")
+	}
+	switch args[0] {
+	case "recent":
+		return runWidgetRecent(args[1:])
+	case "click":
+		return runWidgetClick(args[1:])
+	default:
+		return fmt.Errorf("unknown widget command: %s (expected recent or click)", args[0])
+	}
+}
+
+// runWidgetRecent prints the most recently opened projects for the tmux
+// status line, the current session in bold. On tmux 3.2+ each name is a
+// mouse range that sesh widget click can switch to.
+func runWidgetRecent(args []string) error {
+	limit, current := 3, ""
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--max" || arg == "--current":
+			if i+1 >= len(args) {
+				return fmt.Errorf("%s requires a value", arg)
+			}
+			i++
+			if arg == "--current" {
+				current = args[i]
+				continue
+			}
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid --max %q: expected a positive number", args[i])
+			}
+			limit = n
+		default:
+			return fmt.Errorf("unknown flag: %s", arg)
+		}
+	}
+
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	recent, err := cache.Load()
+	if err != nil {
+		return err
+	}
+	projects := recent.Top()
+	if len(projects) > limit {
+		projects = projects[:limit]
+	}
+
+	ranges := tmux.VersionAtLeast(3, 2)
+	items := make([]string, len(projects))
+	for i, p := range projects {
+		// A literal # would start a tmux style or format
+		item := strings.ReplaceAll(p.Name, "#", "##")
+		if current != "" && tmux.SessionName(finder.Project{Name: p.Name, Path: p.Path, Session: p.Session}) == current {
+			item = "#[bold]" + item + "#[nobold]"
+		}
+		if ranges {
+			item = fmt.Sprintf("#[range=user|%s%d]%s#[norange]", widgetRangePrefix, i+1, item)
+		}
+		items[i] = item
+	}
+	fmt.Println(strings.Join(items, " "))
+	return nil
+}
+
+// runWidgetClick opens the recent project behind a status line range clicked
+// in the recent widget, switching the given client (the current one if left
+// out) to its session
+func runWidgetClick(args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: sesh widget click RANGE [CLIENT]")
+	}
+	n, err := strconv.Atoi(strings.TrimPrefix(args[0], widgetRangePrefix))
+	if err != nil || !strings.HasPrefix(args[0], widgetRangePrefix) || n < 1 {
+		return fmt.Errorf("not a sesh widget range: %s", args[0])
+	}
+	if len(args) == 2 {
+		os.Setenv("SESH_TARGET_CLIENT", args[1])
+	}
+
+	if _, err := loadConfig(); err != nil {
+		return err
+	}
+	recent, err := cache.Load()
+	if err != nil {
+		return err
+	}
+	projects := recent.Top()
+	if n > len(projects) {
+		return fmt.Errorf("no recent project %d", n)
+	}
+	p := projects[n-1]
+	project := finder.Project{Name: p.Name, Path: p.Path}
+	// A session renamed with sesh rename is reopened under its new name
+	if p.Session != "" {
+		if exists, _ := tmux.SessionExists(p.Session); exists {
+			project.Session = p.Session
+		}
+	}
+	return openProject(project)
+}